@@ -0,0 +1,48 @@
+package decodebencode
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// Appends bencode integer i to dst, in the style of strconv.AppendInt
+func AppendInt(dst []byte, i int64) []byte {
+	dst = append(dst, INT_CONTROL_SYMBOL)
+	dst = strconv.AppendInt(dst, i, 10)
+	return append(dst, CLOSE_CONTROL_SYMBOL)
+}
+
+// Appends bencode integer u to dst
+func AppendUint(dst []byte, u uint64) []byte {
+	dst = append(dst, INT_CONTROL_SYMBOL)
+	dst = strconv.AppendUint(dst, u, 10)
+	return append(dst, CLOSE_CONTROL_SYMBOL)
+}
+
+// Appends s as a bencode byte string to dst, the empty string included ("0:")
+func AppendString(dst []byte, s string) []byte {
+	return append(appendLength(dst, len(s)), s...)
+}
+
+// Appends b as a bencode byte string to dst
+func AppendBytes(dst []byte, b []byte) []byte {
+	return append(appendLength(dst, len(b)), b...)
+}
+
+// appends the `length:` prefix of a byte string
+func appendLength(dst []byte, length int) []byte {
+	dst = strconv.AppendInt(dst, int64(length), 10)
+	return append(dst, STR_CONTROL_SYMBOL)
+}
+
+// Appends bencode of anything Marshal accepts to dst. On error dst is
+// returned as it was.
+func AppendValue(dst []byte, v any) ([]byte, error) {
+	e := encodeState{buf: dst}
+
+	if err := e.marshal(reflect.ValueOf(v)); err != nil {
+		return dst, err
+	}
+
+	return e.buf, nil
+}
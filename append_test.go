@@ -0,0 +1,102 @@
+package decodebencode_test
+
+import (
+	"math"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func TestAppendPrimitives(t *testing.T) {
+	type TestCase struct {
+		name     string
+		append   func(dst []byte) []byte
+		expected string
+	}
+
+	testCases := []TestCase{
+		{name: "int", append: func(dst []byte) []byte { return decodebencode.AppendInt(dst, 42) }, expected: "prefix:i42e"},
+		{name: "negative int", append: func(dst []byte) []byte { return decodebencode.AppendInt(dst, math.MinInt64) }, expected: "prefix:i-9223372036854775808e"},
+		{name: "uint", append: func(dst []byte) []byte { return decodebencode.AppendUint(dst, math.MaxUint64) }, expected: "prefix:i18446744073709551615e"},
+		{name: "string", append: func(dst []byte) []byte { return decodebencode.AppendString(dst, "ゴゴ") }, expected: "prefix:6:ゴゴ"},
+		{name: "empty string", append: func(dst []byte) []byte { return decodebencode.AppendString(dst, "") }, expected: "prefix:0:"},
+		{name: "bytes", append: func(dst []byte) []byte { return decodebencode.AppendBytes(dst, []byte{0, 0xff}) }, expected: "prefix:2:\x00\xff"},
+		{
+			name: "value",
+			append: func(dst []byte) []byte {
+				dst, _ = decodebencode.AppendValue(dst, map[string]any{"b": []int{1}, "a": "x"})
+				return dst
+			},
+			expected: "prefix:d1:a1:x1:bli1eee",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := tc.append([]byte("prefix:"))
+			if string(output) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestAppendValueError(t *testing.T) {
+	dst := []byte("prefix:")
+
+	output, err := decodebencode.AppendValue(dst, []any{1, 1.5})
+	if err == nil {
+		t.Fatalf("Expected error, got nil")
+	}
+	if string(output) != "prefix:" {
+		t.Errorf("Expected dst to be returned unchanged, got %q", output)
+	}
+}
+
+func TestAppendDoesNotAllocate(t *testing.T) {
+	buf := make([]byte, 0, 64)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = decodebencode.AppendInt(buf[:0], -12345)
+		buf = decodebencode.AppendString(buf, "announce")
+		buf = decodebencode.AppendBytes(buf, []byte("peer"))
+	})
+
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %v", allocs)
+	}
+}
+
+func BenchmarkEncodeInteger(b *testing.B) {
+	b.Run("EncodeBencodeInteger", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = decodebencode.EncodeBencodeInteger(-12345)
+		}
+	})
+
+	b.Run("AppendInt", func(b *testing.B) {
+		buf := make([]byte, 0, 32)
+		b.ReportAllocs()
+		for b.Loop() {
+			buf = decodebencode.AppendInt(buf[:0], -12345)
+		}
+	})
+}
+
+func BenchmarkEncodeString(b *testing.B) {
+	b.Run("EncodeBencodeString", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			_ = decodebencode.EncodeBencodeString("announce")
+		}
+	})
+
+	b.Run("AppendString", func(b *testing.B) {
+		buf := make([]byte, 0, 32)
+		b.ReportAllocs()
+		for b.Loop() {
+			buf = decodebencode.AppendString(buf[:0], "announce")
+		}
+	})
+}
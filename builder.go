@@ -0,0 +1,229 @@
+package decodebencode
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Builder writes bencode token by token, for outputs too big to be built as a
+// map[string]any first. It checks that lists and dicts are nested properly and
+// that every dict key has a value; in canonical mode it also checks that dict
+// keys come in ascending byte order. The first mistake or write error sticks:
+// every following call returns it.
+//
+//	b := NewBuilder(w)
+//	b.BeginDict()
+//	b.Key("announce")
+//	b.String("http://tracker")
+//	b.Key("info")
+//	b.BeginDict()
+//	...
+//	b.End()
+//	b.End()
+//	err := b.Close()
+type Builder struct {
+	state     encodeState
+	open      []builderFrame
+	canonical bool
+	// the top-level value was started
+	started bool
+	err     error
+}
+
+// open list or dict
+type builderFrame struct {
+	dict bool
+	// dict got a key that has no value yet
+	hasKey  bool
+	lastKey string
+	keys    int
+}
+
+func NewBuilder(w io.Writer) *Builder {
+	return &Builder{state: encodeState{w: w}}
+}
+
+// In canonical mode dict keys must come in ascending byte order, as BEP-3 requires
+func (b *Builder) SetCanonical(canonical bool) {
+	b.canonical = canonical
+}
+
+func (b *Builder) fail(format string, args ...any) error {
+	b.err = fmt.Errorf("bencode builder: "+format, args...)
+	return b.err
+}
+
+// checks that a value may be written now and marks the key it belongs to as used
+func (b *Builder) beforeValue() error {
+	if b.err != nil {
+		return b.err
+	}
+
+	if len(b.open) == 0 {
+		if b.started {
+			return b.fail("second top-level value")
+		}
+		b.started = true
+		return nil
+	}
+
+	top := &b.open[len(b.open)-1]
+	if top.dict {
+		if !top.hasKey {
+			return b.fail("dict value without a key")
+		}
+		top.hasKey = false
+	}
+
+	return nil
+}
+
+func (b *Builder) afterWrite() error {
+	if b.state.err != nil && b.err == nil {
+		b.err = b.state.err
+	}
+	return b.err
+}
+
+func (b *Builder) BeginList() error {
+	if err := b.beforeValue(); err != nil {
+		return err
+	}
+
+	b.open = append(b.open, builderFrame{})
+	b.state.writeByte(LIST_CONTROL_SYMBOL)
+	return b.afterWrite()
+}
+
+func (b *Builder) BeginDict() error {
+	if err := b.beforeValue(); err != nil {
+		return err
+	}
+
+	b.open = append(b.open, builderFrame{dict: true})
+	b.state.writeByte(DICT_CONTROL_SYMBOL)
+	return b.afterWrite()
+}
+
+// Closes the innermost list or dict
+func (b *Builder) End() error {
+	if b.err != nil {
+		return b.err
+	}
+
+	if len(b.open) == 0 {
+		return b.fail("End without an open list or dict")
+	}
+
+	if top := b.open[len(b.open)-1]; top.dict && top.hasKey {
+		return b.fail("dict key %q has no value", top.lastKey)
+	}
+
+	b.open = b.open[:len(b.open)-1]
+	b.state.writeByte(CLOSE_CONTROL_SYMBOL)
+	return b.afterWrite()
+}
+
+// Writes a dict key, the next value belongs to it
+func (b *Builder) Key(k string) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	if len(b.open) == 0 || !b.open[len(b.open)-1].dict {
+		return b.fail("key %q outside of a dict", k)
+	}
+
+	top := &b.open[len(b.open)-1]
+	if top.hasKey {
+		return b.fail("dict key %q has no value", top.lastKey)
+	}
+
+	if b.canonical && top.keys > 0 && k <= top.lastKey {
+		return b.fail("dict key %q is not greater than previous key %q", k, top.lastKey)
+	}
+
+	top.hasKey = true
+	top.lastKey = k
+	top.keys++
+
+	b.state.writeString(k)
+	return b.afterWrite()
+}
+
+func (b *Builder) Int(i int64) error {
+	if err := b.beforeValue(); err != nil {
+		return err
+	}
+
+	b.state.writeInt(i)
+	return b.afterWrite()
+}
+
+func (b *Builder) Uint(u uint64) error {
+	if err := b.beforeValue(); err != nil {
+		return err
+	}
+
+	b.state.writeUint(u)
+	return b.afterWrite()
+}
+
+func (b *Builder) String(s string) error {
+	if err := b.beforeValue(); err != nil {
+		return err
+	}
+
+	b.state.writeString(s)
+	return b.afterWrite()
+}
+
+func (b *Builder) Bytes(p []byte) error {
+	if err := b.beforeValue(); err != nil {
+		return err
+	}
+
+	b.state.writeBytes(p)
+	return b.afterWrite()
+}
+
+// Writes anything Marshal accepts as a single value
+func (b *Builder) Value(v any) error {
+	if err := b.beforeValue(); err != nil {
+		return err
+	}
+
+	if err := b.state.marshal(reflect.ValueOf(v)); err != nil && b.state.err == nil {
+		b.err = err
+	}
+	return b.afterWrite()
+}
+
+// Writes out everything buffered so far
+func (b *Builder) Flush() error {
+	if b.err != nil {
+		return b.err
+	}
+
+	b.state.flush()
+	return b.afterWrite()
+}
+
+// Checks that exactly one value was written, with every list and dict
+// closed, and flushes the output
+func (b *Builder) Close() error {
+	if b.err != nil {
+		return b.err
+	}
+
+	if !b.started {
+		return b.fail("no value was written")
+	}
+
+	if len(b.open) > 0 {
+		return b.fail("%d lists or dicts are not closed", len(b.open))
+	}
+
+	return b.Flush()
+}
@@ -0,0 +1,162 @@
+package decodebencode_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func TestBuilder(t *testing.T) {
+	var out bytes.Buffer
+	b := decodebencode.NewBuilder(&out)
+	b.SetCanonical(true)
+
+	b.BeginDict()
+	b.Key("announce")
+	b.String("http://tracker")
+	b.Key("info")
+	b.BeginDict()
+	b.Key("files")
+	b.BeginList()
+	b.Value(map[string]any{"length": 7, "path": []string{"a"}})
+	b.End()
+	b.Key("length")
+	b.Int(-1)
+	b.Key("pieces")
+	b.Bytes([]byte{0, 0xff})
+	b.Key("size")
+	b.Uint(1 << 63)
+	b.End()
+	b.End()
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "d8:announce14:http://tracker4:infod5:filesld6:lengthi7e4:pathl1:aeee" +
+		"6:lengthi-1e6:pieces2:\x00\xff4:sizei9223372036854775808eee"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	type TestCase struct {
+		name      string
+		canonical bool
+		build     func(b *decodebencode.Builder)
+		expectErr string
+	}
+
+	testCases := []TestCase{
+		{
+			name:      "value without key",
+			build:     func(b *decodebencode.Builder) { b.BeginDict(); b.Int(1) },
+			expectErr: "dict value without a key",
+		},
+		{
+			name:      "key outside of dict",
+			build:     func(b *decodebencode.Builder) { b.BeginList(); b.Key("a") },
+			expectErr: `key "a" outside of a dict`,
+		},
+		{
+			name:      "two keys in a row",
+			build:     func(b *decodebencode.Builder) { b.BeginDict(); b.Key("a"); b.Key("b") },
+			expectErr: `dict key "a" has no value`,
+		},
+		{
+			name:      "dict closed after key",
+			build:     func(b *decodebencode.Builder) { b.BeginDict(); b.Key("a"); b.End() },
+			expectErr: `dict key "a" has no value`,
+		},
+		{
+			name:      "too many ends",
+			build:     func(b *decodebencode.Builder) { b.BeginList(); b.End(); b.End() },
+			expectErr: "End without an open list or dict",
+		},
+		{
+			name:      "not closed",
+			build:     func(b *decodebencode.Builder) { b.BeginList(); b.BeginDict() },
+			expectErr: "2 lists or dicts are not closed",
+		},
+		{
+			name:      "keys out of order in canonical mode",
+			canonical: true,
+			build:     func(b *decodebencode.Builder) { b.BeginDict(); b.Key("b"); b.Int(1); b.Key("a") },
+			expectErr: `dict key "a" is not greater than previous key "b"`,
+		},
+		{
+			name:      "duplicate key in canonical mode",
+			canonical: true,
+			build:     func(b *decodebencode.Builder) { b.BeginDict(); b.Key("a"); b.Int(1); b.Key("a") },
+			expectErr: `dict key "a" is not greater than previous key "a"`,
+		},
+		{
+			name:      "unsupported value",
+			build:     func(b *decodebencode.Builder) { b.BeginList(); b.Value(1.5) },
+			expectErr: "unsupported type float64",
+		},
+		{
+			name:      "second top-level value",
+			build:     func(b *decodebencode.Builder) { b.Int(1); b.Int(2) },
+			expectErr: "second top-level value",
+		},
+		{
+			name:      "second top-level list",
+			build:     func(b *decodebencode.Builder) { b.BeginList(); b.End(); b.BeginList() },
+			expectErr: "second top-level value",
+		},
+		{
+			name:      "nothing written",
+			build:     func(b *decodebencode.Builder) {},
+			expectErr: "no value was written",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := decodebencode.NewBuilder(&bytes.Buffer{})
+			b.SetCanonical(tc.canonical)
+			tc.build(b)
+
+			err := b.Close()
+			if err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tc.expectErr) {
+				t.Errorf("Expected error containing %q, got %q", tc.expectErr, err)
+			}
+			if again := b.Int(1); again != err {
+				t.Errorf("Expected error to stick, got %v", again)
+			}
+		})
+	}
+
+	// keys out of order are fine outside canonical mode
+	b := decodebencode.NewBuilder(&bytes.Buffer{})
+	b.BeginDict()
+	b.Key("b")
+	b.Int(1)
+	b.Key("a")
+	b.Int(2)
+	b.End()
+	if err := b.Close(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestBuilderWriteError(t *testing.T) {
+	w := &limitedWriter{limit: 10}
+	b := decodebencode.NewBuilder(w)
+
+	b.BeginList()
+	b.String(strings.Repeat("x", 10000))
+	b.End()
+
+	if err := b.Close(); !errors.Is(err, errWriterFull) {
+		t.Errorf("Expected %v, got %v", errWriterFull, err)
+	}
+}
@@ -0,0 +1,204 @@
+package decodebencode
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// Something Canonicalize had to change, Offset points to it in the input
+type CanonicalChange struct {
+	Offset      int
+	Path        Path
+	Description string
+}
+
+func (c CanonicalChange) String() string {
+	return fmt.Sprintf("index %d%s: %s", c.Offset, atPath(c.Path), c.Description)
+}
+
+// Re-encodes any bencode DecodeBencode accepts in the canonical form BEP-3
+// asks for: dict keys sorted by raw bytes, integers and string lengths without
+// signs or leading zeros that are not needed, no negative zero. Strings are
+// copied byte for byte, so binary values survive. When a dict key repeats, the
+// last value is kept, as DecodeBencode does.
+func Canonicalize(data []byte) ([]byte, error) {
+	canonical, _, err := CanonicalizeReport(data)
+	return canonical, err
+}
+
+// Same as Canonicalize, but also tells what was changed. Canonical input gives
+// no changes and comes back unchanged.
+func CanonicalizeReport(data []byte) ([]byte, []CanonicalChange, error) {
+	c := canonicalizer{tokens: NewTokenizer(data)}
+
+	tok, err := c.tokens.Next()
+	if err == io.EOF {
+		return nil, nil, errors.New("bencode: cannot canonicalize empty input")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	canonical, err := c.value(nil, tok, Path{}, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c.tokens.Offset() != len(data) {
+		return nil, nil, fmt.Errorf("wrong input data, unexpected data after the value on index %d", c.tokens.Offset())
+	}
+
+	return canonical, c.changes, nil
+}
+
+type canonicalizer struct {
+	tokens  *Tokenizer
+	changes []CanonicalChange
+}
+
+func (c *canonicalizer) change(offset int, path Path, format string, args ...any) {
+	c.changes = append(c.changes, CanonicalChange{Offset: offset, Path: path, Description: fmt.Sprintf(format, args...)})
+}
+
+// path extended with segment, without touching the backing array of path
+func childPath(path Path, segment PathSegment) Path {
+	return append(path[:len(path):len(path)], segment)
+}
+
+// appends canonical form of the value starting with tok to dst
+func (c *canonicalizer) value(dst []byte, tok Token, path Path, depth int) ([]byte, error) {
+	if depth > maxValidDepth {
+		return nil, fmt.Errorf("bencode: nesting deeper than %d on index %d", maxValidDepth, tok.Offset)
+	}
+
+	switch tok.Kind {
+	case TokenInt:
+		digits := canonicalInteger(tok.Value)
+		if !bytes.Equal(digits, tok.Value) {
+			c.change(tok.Offset, path, "integer %s written as i%se", tok.Raw, digits)
+		}
+		dst = append(dst, INT_CONTROL_SYMBOL)
+		dst = append(dst, digits...)
+		return append(dst, CLOSE_CONTROL_SYMBOL), nil
+
+	case TokenString:
+		return c.string(dst, tok, path), nil
+
+	case TokenListStart:
+		dst = append(dst, LIST_CONTROL_SYMBOL)
+		for i := 0; ; i++ {
+			item, err := c.tokens.Next()
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			if item.Kind == TokenEnd {
+				return append(dst, CLOSE_CONTROL_SYMBOL), nil
+			}
+			if dst, err = c.value(dst, item, childPath(path, PathSegment{Index: i, IsIndex: true}), depth+1); err != nil {
+				return nil, err
+			}
+		}
+
+	case TokenDictStart:
+		return c.dict(dst, tok, path, depth)
+	}
+
+	return nil, fmt.Errorf("unexpected %v on index %d", tok.Kind, tok.Offset)
+}
+
+func (c *canonicalizer) string(dst []byte, tok Token, path Path) []byte {
+	length := tok.Raw[:len(tok.Raw)-len(tok.Value)-1]
+	if canonical := strconv.Itoa(len(tok.Value)); string(length) != canonical {
+		c.change(tok.Offset, path, "string length %s written as %s", length, canonical)
+	}
+	return AppendBytes(dst, tok.Value)
+}
+
+func (c *canonicalizer) dict(dst []byte, start Token, path Path, depth int) ([]byte, error) {
+	type entry struct {
+		key    []byte
+		offset int
+		value  []byte
+	}
+
+	var entries []entry
+	for {
+		key, err := c.tokens.Next()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if key.Kind == TokenEnd {
+			break
+		}
+		if key.Kind != TokenString {
+			return nil, fmt.Errorf("value on index %d cannot be used as dict key, it is %v", key.Offset, key.Kind)
+		}
+
+		valuePath := childPath(path, PathSegment{Key: string(key.Value)})
+		c.string(nil, key, valuePath)
+
+		valueTok, err := c.tokens.Next()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if valueTok.Kind == TokenEnd {
+			return nil, fmt.Errorf("dict key %q on index %d has no value", key.Value, key.Offset)
+		}
+
+		value, err := c.value(nil, valueTok, valuePath, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key: key.Value, offset: key.Offset, value: value})
+	}
+
+	byKey := func(a, b entry) int {
+		return bytes.Compare(a.key, b.key)
+	}
+	if !slices.IsSortedFunc(entries, byKey) {
+		c.change(start.Offset, path, "dict keys sorted")
+		slices.SortStableFunc(entries, byKey)
+	}
+
+	dst = append(dst, DICT_CONTROL_SYMBOL)
+	for i, e := range entries {
+		if i+1 < len(entries) && bytes.Equal(e.key, entries[i+1].key) {
+			c.change(e.offset, childPath(path, PathSegment{Key: string(e.key)}), "duplicate dict key %q dropped, the last value is kept", e.key)
+			continue
+		}
+		dst = AppendBytes(dst, e.key)
+		dst = append(dst, e.value...)
+	}
+
+	return append(dst, CLOSE_CONTROL_SYMBOL), nil
+}
+
+// io.EOF in the middle of a value means the input ended too early
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// digits without plus sign and leading zeros, -0 becomes 0
+func canonicalInteger(digits []byte) []byte {
+	negative := digits[0] == '-'
+	if digits[0] == '-' || digits[0] == '+' {
+		digits = digits[1:]
+	}
+
+	for len(digits) > 1 && digits[0] == '0' {
+		digits = digits[1:]
+	}
+
+	if !negative || digits[0] == '0' {
+		return digits
+	}
+
+	return append([]byte{'-'}, digits...)
+}
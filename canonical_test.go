@@ -0,0 +1,143 @@
+package decodebencode_test
+
+import (
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func TestCanonicalize(t *testing.T) {
+	type TestCase struct {
+		name     string
+		input    string
+		expected string
+		changes  []string
+	}
+
+	testCases := []TestCase{
+		{
+			name:     "canonical input is kept",
+			input:    "d1:ai1e1:bl2:\x00\xffi-3eee",
+			expected: "d1:ai1e1:bl2:\x00\xffi-3eee",
+		},
+		{
+			name:     "unsorted keys",
+			input:    "d1:bi1e1:ai2ee",
+			expected: "d1:ai2e1:bi1ee",
+			changes:  []string{"index 0: dict keys sorted"},
+		},
+		{
+			name:     "keys sorted by raw bytes",
+			input:    "d1:\xffi1e1:ai2e1:Bi3ee",
+			expected: "d1:Bi3e1:ai2e1:\xffi1ee",
+			changes:  []string{"index 0: dict keys sorted"},
+		},
+		{
+			name:     "leading zeros",
+			input:    "li007ei-007ee",
+			expected: "li7ei-7ee",
+			changes: []string{
+				"index 1 at `[0]`: integer i007e written as i7e",
+				"index 6 at `[1]`: integer i-007e written as i-7e",
+			},
+		},
+		{
+			name:     "plus sign and negative zero",
+			input:    "d1:ai+5e1:bi-0ee",
+			expected: "d1:ai5e1:bi0ee",
+			changes: []string{
+				"index 4 at `a`: integer i+5e written as i5e",
+				"index 11 at `b`: integer i-0e written as i0e",
+			},
+		},
+		{
+			name:     "integer bigger than int64",
+			input:    "i000123456789012345678901234567890e",
+			expected: "i123456789012345678901234567890e",
+			changes:  []string{"index 0: integer i000123456789012345678901234567890e written as i123456789012345678901234567890e"},
+		},
+		{
+			name:     "string length with leading zeros",
+			input:    "d02:\x00\xff003:abce",
+			expected: "d2:\x00\xff3:abce",
+			changes: []string{
+				"index 1 at `[\"\\x00\\xff\"]`: string length 02 written as 2",
+				"index 6 at `[\"\\x00\\xff\"]`: string length 003 written as 3",
+			},
+		},
+		{
+			name:     "duplicate keys keep the last value",
+			input:    "d1:ai1e1:bi2e1:ai3ee",
+			expected: "d1:ai3e1:bi2ee",
+			changes: []string{
+				"index 0: dict keys sorted",
+				"index 1 at `a`: duplicate dict key \"a\" dropped, the last value is kept",
+			},
+		},
+		{
+			name:     "nested dicts",
+			input:    "d4:infod6:lengthi1e4:name1:xee",
+			expected: "d4:infod6:lengthi1e4:name1:xee",
+		},
+		{
+			name:     "nested unsorted dict",
+			input:    "d4:infod4:name1:x6:lengthi01eee",
+			expected: "d4:infod6:lengthi1e4:name1:xee",
+			changes: []string{
+				"index 25 at `info.length`: integer i01e written as i1e",
+				"index 7 at `info`: dict keys sorted",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, changes, err := decodebencode.CanonicalizeReport([]byte(tc.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if string(output) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+
+			if len(changes) != len(tc.changes) {
+				t.Fatalf("Expected changes %q, got %v", tc.changes, changes)
+			}
+			for i, change := range changes {
+				if change.String() != tc.changes[i] {
+					t.Errorf("Expected change %q, got %q", tc.changes[i], change.String())
+				}
+			}
+
+			if !decodebencode.Valid(output) {
+				t.Errorf("Output %q is not valid canonical bencode", output)
+			}
+
+			again, err := decodebencode.Canonicalize(output)
+			if err != nil || string(again) != string(output) {
+				t.Errorf("Canonicalize is not idempotent: %q, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeErrors(t *testing.T) {
+	testCases := map[string]string{
+		"empty input":              "",
+		"integer key":              "di1ei2ee",
+		"key without value":        "d1:ae",
+		"unclosed dict":            "d1:ai1e",
+		"trailing data":            "i1ei2e",
+		"string too short":         "4:abc",
+		"unmatched closing symbol": "e",
+	}
+
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			if output, err := decodebencode.Canonicalize([]byte(input)); err == nil {
+				t.Errorf("Expected an error, got %q", output)
+			}
+		})
+	}
+}
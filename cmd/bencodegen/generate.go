@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/types"
+	"slices"
+	"strconv"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+// how the generated code handles a type
+type kind int
+
+const (
+	// left to decodebencode.AppendValue and decodebencode.Unmarshal
+	kindReflect kind = iota
+	kindString
+	kindInt
+	kindUint
+	kindBytes
+	kindByteArray
+	kindSlice
+	kindMap
+	// picked struct type
+	kindStruct
+	// pointer to picked struct type
+	kindPointer
+)
+
+type genType struct {
+	kind kind
+	// Go source of the type
+	src  string
+	elem *genType
+}
+
+var (
+	intTypes  = []string{"int", "int8", "int16", "int32", "int64", "rune"}
+	uintTypes = []string{"uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte"}
+)
+
+func isByte(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && (ident.Name == "byte" || ident.Name == "uint8")
+}
+
+func (g *generator) resolve(expr ast.Expr) *genType {
+	t := &genType{src: types.ExprString(expr)}
+
+	switch e := expr.(type) {
+	case *ast.Ident:
+		switch {
+		case e.Name == "string":
+			t.kind = kindString
+		case slices.Contains(intTypes, e.Name):
+			t.kind = kindInt
+		case slices.Contains(uintTypes, e.Name):
+			t.kind = kindUint
+		case g.pkg.picked[e.Name]:
+			t.kind = kindStruct
+		}
+
+	case *ast.ArrayType:
+		switch {
+		case e.Len == nil && isByte(e.Elt):
+			t.kind = kindBytes
+		case e.Len == nil:
+			t.kind = kindSlice
+			t.elem = g.resolve(e.Elt)
+		case isByte(e.Elt):
+			t.kind = kindByteArray
+		}
+
+	case *ast.MapType:
+		if key, ok := e.Key.(*ast.Ident); ok && key.Name == "string" {
+			t.kind = kindMap
+			t.elem = g.resolve(e.Value)
+		}
+
+	case *ast.StarExpr:
+		if elem := g.resolve(e.X); elem.kind == kindStruct {
+			t.kind = kindPointer
+			t.elem = elem
+		}
+	}
+
+	return t
+}
+
+type generator struct {
+	pkg *genPackage
+	out *bytes.Buffer
+	// whether the function being written needs err and start variables
+	usesErr bool
+	// whether bencodegenIsEmpty has to be written
+	usesIsEmpty bool
+	usesSlices  bool
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(g.out, format, args...)
+}
+
+// Returns gofmt-ed source of the file with the methods of every type of pkg
+func generate(pkg *genPackage) ([]byte, error) {
+	g := &generator{pkg: pkg}
+
+	var body bytes.Buffer
+	g.out = &body
+	for _, st := range pkg.types {
+		g.writeStruct(st)
+	}
+	g.writeHelpers()
+
+	var file bytes.Buffer
+	g.out = &file
+	g.printf("// Code generated by bencodegen. DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", pkg.name)
+	g.printf("import (\n\"errors\"\n\"fmt\"\n\"io\"\n\"reflect\"\n")
+	if g.usesSlices {
+		g.printf("\"slices\"\n")
+	}
+	g.printf("\ndecodebencode \"github.com/jabakot/decode-bencode\"\n)\n\n")
+	file.Write(body.Bytes())
+
+	src, err := format.Source(file.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, file.Bytes())
+	}
+	return src, nil
+}
+
+func (g *generator) writeStruct(st *genStruct) {
+	g.writeAppend(st)
+
+	g.printf("// MarshalBencode implements decodebencode.Marshaler.\n")
+	g.printf("func (v %s) MarshalBencode() ([]byte, error) {\nreturn v.AppendBencode(nil)\n}\n\n", st.name)
+
+	g.writeUnmarshal(st)
+}
+
+func (g *generator) writeAppend(st *genStruct) {
+	out := g.out
+	var body bytes.Buffer
+	g.out = &body
+	g.usesErr = false
+
+	g.printf("dst = append(dst, 'd')\n")
+	for _, f := range st.fields {
+		t := g.resolve(f.typ)
+		x := "v." + f.goName
+
+		nonEmpty := ""
+		if f.omitEmpty {
+			nonEmpty = g.nonEmpty(t, x)
+		}
+		if nonEmpty != "" {
+			g.printf("if %s {\n", nonEmpty)
+		}
+
+		g.printf("dst = append(dst, %s...)\n", strconv.Quote(string(decodebencode.AppendString(nil, f.key))))
+		if nonEmpty != "" && t.kind == kindPointer {
+			// already known not to be nil
+			g.encode(t.elem, x, 1)
+		} else {
+			g.encode(t, x, 1)
+		}
+
+		if nonEmpty != "" {
+			g.printf("}\n")
+		}
+	}
+	g.printf("dst = append(dst, 'e')\nreturn dst, nil\n")
+
+	g.out = out
+	g.printf("// AppendBencode appends bencode of v to dst, as decodebencode.AppendValue\n")
+	g.printf("// would. On error dst is returned as it was.\n")
+	g.printf("func (v %s) AppendBencode(dst []byte) ([]byte, error) {\n", st.name)
+	if g.usesErr {
+		g.printf("start := len(dst)\nvar err error\n")
+	}
+	g.out.Write(body.Bytes())
+	g.printf("}\n\n")
+}
+
+// condition telling omitempty keeps x, "" when it always does
+func (g *generator) nonEmpty(t *genType, x string) string {
+	switch t.kind {
+	case kindString, kindBytes, kindByteArray, kindSlice, kindMap:
+		return "len(" + x + ") != 0"
+	case kindInt, kindUint:
+		return x + " != 0"
+	case kindPointer:
+		return x + " != nil"
+	case kindReflect:
+		g.usesIsEmpty = true
+		return "!bencodegenIsEmpty(reflect.ValueOf(&" + x + ").Elem())"
+	}
+	return ""
+}
+
+// writes code appending bencode of addressable x of type t to dst
+func (g *generator) encode(t *genType, x string, depth int) {
+	switch t.kind {
+	case kindString:
+		g.printf("dst = decodebencode.AppendString(dst, %s)\n", x)
+
+	case kindInt:
+		g.printf("dst = decodebencode.AppendInt(dst, int64(%s))\n", x)
+
+	case kindUint:
+		g.printf("dst = decodebencode.AppendUint(dst, uint64(%s))\n", x)
+
+	case kindBytes:
+		g.printf("dst = decodebencode.AppendBytes(dst, %s)\n", x)
+
+	case kindByteArray:
+		g.printf("dst = decodebencode.AppendBytes(dst, %s[:])\n", x)
+
+	case kindSlice:
+		e := fmt.Sprintf("e%d", depth)
+		g.printf("dst = append(dst, 'l')\n")
+		g.printf("for _, %s := range %s {\n", e, x)
+		g.encode(t.elem, e, depth+1)
+		g.printf("}\ndst = append(dst, 'e')\n")
+
+	case kindMap:
+		g.usesSlices = true
+		keys, k, e := fmt.Sprintf("keys%d", depth), fmt.Sprintf("k%d", depth), fmt.Sprintf("e%d", depth)
+		g.printf("%s := make([]string, 0, len(%s))\n", keys, x)
+		g.printf("for %s := range %s {\n%s = append(%s, %s)\n}\n", k, x, keys, keys, k)
+		g.printf("slices.Sort(%s)\n", keys)
+		g.printf("dst = append(dst, 'd')\n")
+		g.printf("for _, %s := range %s {\n", k, keys)
+		g.printf("dst = decodebencode.AppendString(dst, %s)\n", k)
+		g.printf("%s := %s[%s]\n", e, x, k)
+		g.encode(t.elem, e, depth+1)
+		g.printf("}\ndst = append(dst, 'e')\n")
+
+	case kindStruct:
+		g.usesErr = true
+		g.printf("if dst, err = %s.AppendBencode(dst); err != nil {\nreturn dst[:start], err\n}\n", x)
+
+	case kindPointer:
+		g.usesErr = true
+		g.printf("if %s == nil {\n", x)
+		g.printf("return dst[:start], &decodebencode.UnsupportedValueError{Value: reflect.ValueOf(%s), Str: %s}\n", x, strconv.Quote("nil *"+g.pkg.name+"."+t.elem.src))
+		g.printf("}\n")
+		g.encode(t.elem, x, depth)
+
+	case kindReflect:
+		g.usesErr = true
+		g.printf("if dst, err = decodebencode.AppendValue(dst, &%s); err != nil {\nreturn dst[:start], err\n}\n", x)
+	}
+}
+
+func (g *generator) writeUnmarshal(st *genStruct) {
+	g.printf("// UnmarshalBencode implements decodebencode.Unmarshaler.\n")
+	g.printf("func (v *%s) UnmarshalBencode(data []byte) error {\n", st.name)
+	g.printf(`t := decodebencode.NewTokenizer(data)
+tok, err := t.Next()
+if err == io.EOF {
+	return errors.New("bencode: cannot unmarshal empty input")
+}
+if err != nil {
+	return err
+}
+if err := v.unmarshalBencodeToken(t, tok); err != nil {
+	return err
+}
+if t.Offset() != len(data) {
+	return fmt.Errorf("bencode: unexpected data after the value on index %%d", t.Offset())
+}
+return nil
+}
+
+`)
+
+	g.printf("// decodes the dict tok starts, tok being the last token t returned\n")
+	g.printf("func (v *%s) unmarshalBencodeToken(t *decodebencode.Tokenizer, tok decodebencode.Token) error {\n", st.name)
+	g.printf("if tok.Kind != decodebencode.TokenDictStart {\nreturn bencodegenTypeError(tok, reflect.TypeFor[%s]())\n}\n", st.name)
+
+	for _, f := range st.fields {
+		if f.required {
+			g.printf("has%s := false\n", f.goName)
+		}
+	}
+
+	g.printf("for {\n")
+	g.writeNextEntry("key", "tok")
+	g.printf("switch string(key.Value) {\n")
+	for _, f := range st.fields {
+		g.printf("case %s:\n", strconv.Quote(f.key))
+		g.decode(g.resolve(f.typ), "v."+f.goName, "tok", 1)
+		if f.required {
+			g.printf("has%s = true\n", f.goName)
+		}
+	}
+	g.printf("default:\nif _, err := t.SkipValue(tok); err != nil {\nreturn err\n}\n")
+	g.printf("}\n}\n")
+
+	for _, f := range st.fields {
+		if f.required {
+			g.printf("if !has%s {\nreturn &decodebencode.RequiredKeyError{Key: %s, Type: reflect.TypeFor[%s]()}\n}\n", f.goName, strconv.Quote(f.key), st.name)
+		}
+	}
+	g.printf("return nil\n}\n\n")
+}
+
+// Reads the next dict key to key and its value to tok, breaking out of the
+// loop at the end of the dict. Declares key, tok is declared when it is not
+// "tok".
+func (g *generator) writeNextEntry(key, tok string) {
+	g.printf(`%s, err := t.Next()
+if err != nil {
+	return err
+}
+if %s.Kind == decodebencode.TokenEnd {
+	break
+}
+if %s.Kind != decodebencode.TokenString {
+	return fmt.Errorf("bencode: %%v on index %%d cannot be a dict key", %s.Kind, %s.Offset)
+}
+`, key, key, key, key, key)
+
+	assign := ":="
+	if tok == "tok" {
+		assign = "="
+	}
+	g.printf(`%s, err %s t.Next()
+if err != nil {
+	return err
+}
+if %s.Kind == decodebencode.TokenEnd {
+	return fmt.Errorf("bencode: dict key %%q on index %%d has no value", %s.Value, %s.Offset)
+}
+`, tok, assign, tok, key, key)
+}
+
+// writes code decoding the value tok starts to addressable x of type t
+func (g *generator) decode(t *genType, x, tok string, depth int) {
+	expect := func(kind string) {
+		g.printf("if %s.Kind != decodebencode.%s {\nreturn bencodegenTypeError(%s, reflect.TypeFor[%s]())\n}\n", tok, kind, tok, t.src)
+	}
+
+	switch t.kind {
+	case kindString:
+		expect("TokenString")
+		g.printf("%s = string(%s.Value)\n", x, tok)
+
+	case kindInt, kindUint:
+		n := fmt.Sprintf("n%d", depth)
+		expect("TokenInt")
+		g.printf("%s, err := %s.Int()\nif err != nil {\nreturn err\n}\n", n, tok)
+
+		var overflow string
+		switch {
+		case t.kind == kindInt && t.src != "int" && t.src != "int64":
+			overflow = fmt.Sprintf("int(%s(%s)) != %s", t.src, n, n)
+		case t.kind == kindUint && (t.src == "uint" || t.src == "uint64" || t.src == "uintptr"):
+			overflow = n + " < 0"
+		case t.kind == kindUint:
+			overflow = fmt.Sprintf("%s < 0 || int(%s(%s)) != %s", n, t.src, n, n)
+		}
+		if overflow != "" {
+			g.printf("if %s {\nreturn bencodegenTypeError(%s, reflect.TypeFor[%s]())\n}\n", overflow, tok, t.src)
+		}
+		g.printf("%s = %s(%s)\n", x, t.src, n)
+
+	case kindBytes:
+		expect("TokenString")
+		g.printf("%s = append([]byte{}, %s.Value...)\n", x, tok)
+
+	case kindByteArray:
+		expect("TokenString")
+		g.printf("if len(%s.Value) != len(%s) {\nreturn bencodegenTypeError(%s, reflect.TypeFor[%s]())\n}\n", tok, x, tok, t.src)
+		g.printf("copy(%s[:], %s.Value)\n", x, tok)
+
+	case kindSlice:
+		item, e := fmt.Sprintf("tok%d", depth), fmt.Sprintf("e%d", depth)
+		expect("TokenListStart")
+		g.printf("if %s == nil {\n%s = %s{}\n} else {\n%s = %s[:0]\n}\n", x, x, t.src, x, x)
+		g.printf("for {\n")
+		g.printf("%s, err := t.Next()\nif err != nil {\nreturn err\n}\n", item)
+		g.printf("if %s.Kind == decodebencode.TokenEnd {\nbreak\n}\n", item)
+		g.printf("var %s %s\n", e, t.elem.src)
+		g.decode(t.elem, e, item, depth+1)
+		g.printf("%s = append(%s, %s)\n}\n", x, x, e)
+
+	case kindMap:
+		key, item, e := fmt.Sprintf("key%d", depth), fmt.Sprintf("tok%d", depth), fmt.Sprintf("e%d", depth)
+		expect("TokenDictStart")
+		g.printf("if %s == nil {\n%s = make(%s)\n}\n", x, x, t.src)
+		g.printf("for {\n")
+		g.writeNextEntry(key, item)
+		g.printf("var %s %s\n", e, t.elem.src)
+		g.decode(t.elem, e, item, depth+1)
+		g.printf("%s[string(%s.Value)] = %s\n}\n", x, key, e)
+
+	case kindStruct:
+		g.printf("if err := %s.unmarshalBencodeToken(t, %s); err != nil {\nreturn err\n}\n", x, tok)
+
+	case kindPointer:
+		g.printf("if %s == nil {\n%s = new(%s)\n}\n", x, x, t.elem.src)
+		g.decode(t.elem, x, tok, depth)
+
+	case kindReflect:
+		raw := fmt.Sprintf("raw%d", depth)
+		g.printf("%s, err := t.SkipValue(%s)\nif err != nil {\nreturn err\n}\n", raw, tok)
+		g.printf("if err := decodebencode.Unmarshal(%s, &%s); err != nil {\nreturn err\n}\n", raw, x)
+	}
+}
+
+func (g *generator) writeHelpers() {
+	g.printf(`func bencodegenTypeError(tok decodebencode.Token, t reflect.Type) error {
+	value := tok.Kind.String()
+	switch tok.Kind {
+	case decodebencode.TokenInt:
+		value = "integer " + string(tok.Value)
+	case decodebencode.TokenListStart:
+		value = "list"
+	case decodebencode.TokenDictStart:
+		value = "dict"
+	}
+	return &decodebencode.UnmarshalTypeError{Value: value, Type: t}
+}
+`)
+
+	if !g.usesIsEmpty {
+		return
+	}
+
+	g.printf(`
+// tells whether omitempty leaves v out, as Marshal does
+func bencodegenIsEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
+`)
+}
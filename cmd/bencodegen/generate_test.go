@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bencode_gen.go of the example package must be what the generator writes now
+func TestGenerateExampleIsUpToDate(t *testing.T) {
+	dir := filepath.Join("internal", "example")
+
+	pkg, err := parsePackage(dir, "bencode_gen.go")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	src, err := generate(pkg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	committed, err := os.ReadFile(filepath.Join(dir, "bencode_gen.go"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(src, committed) {
+		t.Errorf("%s/bencode_gen.go is out of date, run go generate ./...", dir)
+	}
+}
+
+func TestParsePackage(t *testing.T) {
+	dir := t.TempDir()
+	src := `package msgs
+
+//bencode:generate
+type Ping struct {
+	ID       []byte ` + "`bencode:\"id\"`" + `
+	Both     int    ` + "`bencode:\"same\"`" + `
+	Same     int
+	A, B     string
+	Skipped  string ` + "`bencode:\"-\"`" + `
+	Dash     string ` + "`bencode:\"-,\"`" + `
+	internal string
+}
+
+// not picked
+type Pong struct {
+	ID []byte
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "msgs.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pkg, err := parsePackage(dir, "bencode_gen.go")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if pkg.name != "msgs" || len(pkg.types) != 1 || pkg.types[0].name != "Ping" {
+		t.Fatalf("Expected package msgs with type Ping, got %+v", pkg)
+	}
+
+	var keys []string
+	for _, f := range pkg.types[0].fields {
+		keys = append(keys, f.key+"="+f.goName)
+	}
+
+	expected := "-=Dash A=A B=B Same=Same id=ID same=Both"
+	if strings.Join(keys, " ") != expected {
+		t.Errorf("Expected fields %q, got %q", expected, strings.Join(keys, " "))
+	}
+}
+
+func TestParsePackageErrors(t *testing.T) {
+	testCases := map[string]string{
+		"inline field":   "//bencode:generate\ntype T struct {\n\tU U `bencode:\",inline\"`\n}\ntype U struct{}\n",
+		"embedded field": "//bencode:generate\ntype T struct {\n\tU\n}\ntype U struct{}\n",
+		"not a struct":   "//bencode:generate\ntype T []int\n",
+		"nothing picked": "type T struct{}\n",
+	}
+
+	for name, src := range testCases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "t.go"), []byte("package p\n\n"+src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := parsePackage(dir, "bencode_gen.go"); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
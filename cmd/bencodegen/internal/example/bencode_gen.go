@@ -0,0 +1,893 @@
+// Code generated by bencodegen. DO NOT EDIT.
+
+package example
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+// AppendBencode appends bencode of v to dst, as decodebencode.AppendValue
+// would. On error dst is returned as it was.
+func (v AnnounceResponse) AppendBencode(dst []byte) ([]byte, error) {
+	start := len(dst)
+	var err error
+	dst = append(dst, 'd')
+	dst = append(dst, "8:complete"...)
+	dst = decodebencode.AppendInt(dst, int64(v.Complete))
+	if len(v.Extra) != 0 {
+		dst = append(dst, "5:extra"...)
+		keys1 := make([]string, 0, len(v.Extra))
+		for k1 := range v.Extra {
+			keys1 = append(keys1, k1)
+		}
+		slices.Sort(keys1)
+		dst = append(dst, 'd')
+		for _, k1 := range keys1 {
+			dst = decodebencode.AppendString(dst, k1)
+			e1 := v.Extra[k1]
+			if dst, err = decodebencode.AppendValue(dst, &e1); err != nil {
+				return dst[:start], err
+			}
+		}
+		dst = append(dst, 'e')
+	}
+	if len(v.FailureReason) != 0 {
+		dst = append(dst, "14:failure reason"...)
+		dst = decodebencode.AppendString(dst, v.FailureReason)
+	}
+	dst = append(dst, "10:incomplete"...)
+	dst = decodebencode.AppendUint(dst, uint64(v.Incomplete))
+	dst = append(dst, "8:interval"...)
+	dst = decodebencode.AppendInt(dst, int64(v.Interval))
+	if !bencodegenIsEmpty(reflect.ValueOf(&v.MinInterval).Elem()) {
+		dst = append(dst, "12:min interval"...)
+		if dst, err = decodebencode.AppendValue(dst, &v.MinInterval); err != nil {
+			return dst[:start], err
+		}
+	}
+	if len(v.Mirrors) != 0 {
+		dst = append(dst, "7:mirrors"...)
+		keys1 := make([]string, 0, len(v.Mirrors))
+		for k1 := range v.Mirrors {
+			keys1 = append(keys1, k1)
+		}
+		slices.Sort(keys1)
+		dst = append(dst, 'd')
+		for _, k1 := range keys1 {
+			dst = decodebencode.AppendString(dst, k1)
+			e1 := v.Mirrors[k1]
+			if e1 == nil {
+				return dst[:start], &decodebencode.UnsupportedValueError{Value: reflect.ValueOf(e1), Str: "nil *example.Tracker"}
+			}
+			if dst, err = e1.AppendBencode(dst); err != nil {
+				return dst[:start], err
+			}
+		}
+		dst = append(dst, 'e')
+	}
+	dst = append(dst, "5:peers"...)
+	dst = append(dst, 'l')
+	for _, e1 := range v.Peers {
+		if dst, err = e1.AppendBencode(dst); err != nil {
+			return dst[:start], err
+		}
+	}
+	dst = append(dst, 'e')
+	if len(v.Peers6) != 0 {
+		dst = append(dst, "6:peers6"...)
+		dst = decodebencode.AppendBytes(dst, v.Peers6)
+	}
+	if len(v.Stats) != 0 {
+		dst = append(dst, "5:stats"...)
+		keys1 := make([]string, 0, len(v.Stats))
+		for k1 := range v.Stats {
+			keys1 = append(keys1, k1)
+		}
+		slices.Sort(keys1)
+		dst = append(dst, 'd')
+		for _, k1 := range keys1 {
+			dst = decodebencode.AppendString(dst, k1)
+			e1 := v.Stats[k1]
+			dst = decodebencode.AppendBytes(dst, e1)
+		}
+		dst = append(dst, 'e')
+	}
+	if v.Tracker != nil {
+		dst = append(dst, "7:tracker"...)
+		if dst, err = v.Tracker.AppendBencode(dst); err != nil {
+			return dst[:start], err
+		}
+	}
+	dst = append(dst, "7:updated"...)
+	if dst, err = decodebencode.AppendValue(dst, &v.Updated); err != nil {
+		return dst[:start], err
+	}
+	dst = append(dst, "7:version"...)
+	if dst, err = decodebencode.AppendValue(dst, &v.Version); err != nil {
+		return dst[:start], err
+	}
+	dst = append(dst, 'e')
+	return dst, nil
+}
+
+// MarshalBencode implements decodebencode.Marshaler.
+func (v AnnounceResponse) MarshalBencode() ([]byte, error) {
+	return v.AppendBencode(nil)
+}
+
+// UnmarshalBencode implements decodebencode.Unmarshaler.
+func (v *AnnounceResponse) UnmarshalBencode(data []byte) error {
+	t := decodebencode.NewTokenizer(data)
+	tok, err := t.Next()
+	if err == io.EOF {
+		return errors.New("bencode: cannot unmarshal empty input")
+	}
+	if err != nil {
+		return err
+	}
+	if err := v.unmarshalBencodeToken(t, tok); err != nil {
+		return err
+	}
+	if t.Offset() != len(data) {
+		return fmt.Errorf("bencode: unexpected data after the value on index %d", t.Offset())
+	}
+	return nil
+}
+
+// decodes the dict tok starts, tok being the last token t returned
+func (v *AnnounceResponse) unmarshalBencodeToken(t *decodebencode.Tokenizer, tok decodebencode.Token) error {
+	if tok.Kind != decodebencode.TokenDictStart {
+		return bencodegenTypeError(tok, reflect.TypeFor[AnnounceResponse]())
+	}
+	hasInterval := false
+	for {
+		key, err := t.Next()
+		if err != nil {
+			return err
+		}
+		if key.Kind == decodebencode.TokenEnd {
+			break
+		}
+		if key.Kind != decodebencode.TokenString {
+			return fmt.Errorf("bencode: %v on index %d cannot be a dict key", key.Kind, key.Offset)
+		}
+		tok, err = t.Next()
+		if err != nil {
+			return err
+		}
+		if tok.Kind == decodebencode.TokenEnd {
+			return fmt.Errorf("bencode: dict key %q on index %d has no value", key.Value, key.Offset)
+		}
+		switch string(key.Value) {
+		case "complete":
+			if tok.Kind != decodebencode.TokenInt {
+				return bencodegenTypeError(tok, reflect.TypeFor[int64]())
+			}
+			n1, err := tok.Int()
+			if err != nil {
+				return err
+			}
+			v.Complete = int64(n1)
+		case "extra":
+			if tok.Kind != decodebencode.TokenDictStart {
+				return bencodegenTypeError(tok, reflect.TypeFor[map[string]any]())
+			}
+			if v.Extra == nil {
+				v.Extra = make(map[string]any)
+			}
+			for {
+				key1, err := t.Next()
+				if err != nil {
+					return err
+				}
+				if key1.Kind == decodebencode.TokenEnd {
+					break
+				}
+				if key1.Kind != decodebencode.TokenString {
+					return fmt.Errorf("bencode: %v on index %d cannot be a dict key", key1.Kind, key1.Offset)
+				}
+				tok1, err := t.Next()
+				if err != nil {
+					return err
+				}
+				if tok1.Kind == decodebencode.TokenEnd {
+					return fmt.Errorf("bencode: dict key %q on index %d has no value", key1.Value, key1.Offset)
+				}
+				var e1 any
+				raw2, err := t.SkipValue(tok1)
+				if err != nil {
+					return err
+				}
+				if err := decodebencode.Unmarshal(raw2, &e1); err != nil {
+					return err
+				}
+				v.Extra[string(key1.Value)] = e1
+			}
+		case "failure reason":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[string]())
+			}
+			v.FailureReason = string(tok.Value)
+		case "incomplete":
+			if tok.Kind != decodebencode.TokenInt {
+				return bencodegenTypeError(tok, reflect.TypeFor[uint32]())
+			}
+			n1, err := tok.Int()
+			if err != nil {
+				return err
+			}
+			if n1 < 0 || int(uint32(n1)) != n1 {
+				return bencodegenTypeError(tok, reflect.TypeFor[uint32]())
+			}
+			v.Incomplete = uint32(n1)
+		case "interval":
+			if tok.Kind != decodebencode.TokenInt {
+				return bencodegenTypeError(tok, reflect.TypeFor[int]())
+			}
+			n1, err := tok.Int()
+			if err != nil {
+				return err
+			}
+			v.Interval = int(n1)
+			hasInterval = true
+		case "min interval":
+			raw1, err := t.SkipValue(tok)
+			if err != nil {
+				return err
+			}
+			if err := decodebencode.Unmarshal(raw1, &v.MinInterval); err != nil {
+				return err
+			}
+		case "mirrors":
+			if tok.Kind != decodebencode.TokenDictStart {
+				return bencodegenTypeError(tok, reflect.TypeFor[map[string]*Tracker]())
+			}
+			if v.Mirrors == nil {
+				v.Mirrors = make(map[string]*Tracker)
+			}
+			for {
+				key1, err := t.Next()
+				if err != nil {
+					return err
+				}
+				if key1.Kind == decodebencode.TokenEnd {
+					break
+				}
+				if key1.Kind != decodebencode.TokenString {
+					return fmt.Errorf("bencode: %v on index %d cannot be a dict key", key1.Kind, key1.Offset)
+				}
+				tok1, err := t.Next()
+				if err != nil {
+					return err
+				}
+				if tok1.Kind == decodebencode.TokenEnd {
+					return fmt.Errorf("bencode: dict key %q on index %d has no value", key1.Value, key1.Offset)
+				}
+				var e1 *Tracker
+				if e1 == nil {
+					e1 = new(Tracker)
+				}
+				if err := e1.unmarshalBencodeToken(t, tok1); err != nil {
+					return err
+				}
+				v.Mirrors[string(key1.Value)] = e1
+			}
+		case "peers":
+			if tok.Kind != decodebencode.TokenListStart {
+				return bencodegenTypeError(tok, reflect.TypeFor[[]Peer]())
+			}
+			if v.Peers == nil {
+				v.Peers = []Peer{}
+			} else {
+				v.Peers = v.Peers[:0]
+			}
+			for {
+				tok1, err := t.Next()
+				if err != nil {
+					return err
+				}
+				if tok1.Kind == decodebencode.TokenEnd {
+					break
+				}
+				var e1 Peer
+				if err := e1.unmarshalBencodeToken(t, tok1); err != nil {
+					return err
+				}
+				v.Peers = append(v.Peers, e1)
+			}
+		case "peers6":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[[]byte]())
+			}
+			v.Peers6 = append([]byte{}, tok.Value...)
+		case "stats":
+			if tok.Kind != decodebencode.TokenDictStart {
+				return bencodegenTypeError(tok, reflect.TypeFor[map[string][]uint8]())
+			}
+			if v.Stats == nil {
+				v.Stats = make(map[string][]uint8)
+			}
+			for {
+				key1, err := t.Next()
+				if err != nil {
+					return err
+				}
+				if key1.Kind == decodebencode.TokenEnd {
+					break
+				}
+				if key1.Kind != decodebencode.TokenString {
+					return fmt.Errorf("bencode: %v on index %d cannot be a dict key", key1.Kind, key1.Offset)
+				}
+				tok1, err := t.Next()
+				if err != nil {
+					return err
+				}
+				if tok1.Kind == decodebencode.TokenEnd {
+					return fmt.Errorf("bencode: dict key %q on index %d has no value", key1.Value, key1.Offset)
+				}
+				var e1 []uint8
+				if tok1.Kind != decodebencode.TokenString {
+					return bencodegenTypeError(tok1, reflect.TypeFor[[]uint8]())
+				}
+				e1 = append([]byte{}, tok1.Value...)
+				v.Stats[string(key1.Value)] = e1
+			}
+		case "tracker":
+			if v.Tracker == nil {
+				v.Tracker = new(Tracker)
+			}
+			if err := v.Tracker.unmarshalBencodeToken(t, tok); err != nil {
+				return err
+			}
+		case "updated":
+			raw1, err := t.SkipValue(tok)
+			if err != nil {
+				return err
+			}
+			if err := decodebencode.Unmarshal(raw1, &v.Updated); err != nil {
+				return err
+			}
+		case "version":
+			raw1, err := t.SkipValue(tok)
+			if err != nil {
+				return err
+			}
+			if err := decodebencode.Unmarshal(raw1, &v.Version); err != nil {
+				return err
+			}
+		default:
+			if _, err := t.SkipValue(tok); err != nil {
+				return err
+			}
+		}
+	}
+	if !hasInterval {
+		return &decodebencode.RequiredKeyError{Key: "interval", Type: reflect.TypeFor[AnnounceResponse]()}
+	}
+	return nil
+}
+
+// AppendBencode appends bencode of v to dst, as decodebencode.AppendValue
+// would. On error dst is returned as it was.
+func (v Peer) AppendBencode(dst []byte) ([]byte, error) {
+	dst = append(dst, 'd')
+	dst = append(dst, "2:ip"...)
+	dst = decodebencode.AppendString(dst, v.IP)
+	dst = append(dst, "7:peer id"...)
+	dst = decodebencode.AppendBytes(dst, v.ID)
+	dst = append(dst, "4:port"...)
+	dst = decodebencode.AppendUint(dst, uint64(v.Port))
+	dst = append(dst, 'e')
+	return dst, nil
+}
+
+// MarshalBencode implements decodebencode.Marshaler.
+func (v Peer) MarshalBencode() ([]byte, error) {
+	return v.AppendBencode(nil)
+}
+
+// UnmarshalBencode implements decodebencode.Unmarshaler.
+func (v *Peer) UnmarshalBencode(data []byte) error {
+	t := decodebencode.NewTokenizer(data)
+	tok, err := t.Next()
+	if err == io.EOF {
+		return errors.New("bencode: cannot unmarshal empty input")
+	}
+	if err != nil {
+		return err
+	}
+	if err := v.unmarshalBencodeToken(t, tok); err != nil {
+		return err
+	}
+	if t.Offset() != len(data) {
+		return fmt.Errorf("bencode: unexpected data after the value on index %d", t.Offset())
+	}
+	return nil
+}
+
+// decodes the dict tok starts, tok being the last token t returned
+func (v *Peer) unmarshalBencodeToken(t *decodebencode.Tokenizer, tok decodebencode.Token) error {
+	if tok.Kind != decodebencode.TokenDictStart {
+		return bencodegenTypeError(tok, reflect.TypeFor[Peer]())
+	}
+	for {
+		key, err := t.Next()
+		if err != nil {
+			return err
+		}
+		if key.Kind == decodebencode.TokenEnd {
+			break
+		}
+		if key.Kind != decodebencode.TokenString {
+			return fmt.Errorf("bencode: %v on index %d cannot be a dict key", key.Kind, key.Offset)
+		}
+		tok, err = t.Next()
+		if err != nil {
+			return err
+		}
+		if tok.Kind == decodebencode.TokenEnd {
+			return fmt.Errorf("bencode: dict key %q on index %d has no value", key.Value, key.Offset)
+		}
+		switch string(key.Value) {
+		case "ip":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[string]())
+			}
+			v.IP = string(tok.Value)
+		case "peer id":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[[]byte]())
+			}
+			v.ID = append([]byte{}, tok.Value...)
+		case "port":
+			if tok.Kind != decodebencode.TokenInt {
+				return bencodegenTypeError(tok, reflect.TypeFor[uint16]())
+			}
+			n1, err := tok.Int()
+			if err != nil {
+				return err
+			}
+			if n1 < 0 || int(uint16(n1)) != n1 {
+				return bencodegenTypeError(tok, reflect.TypeFor[uint16]())
+			}
+			v.Port = uint16(n1)
+		default:
+			if _, err := t.SkipValue(tok); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AppendBencode appends bencode of v to dst, as decodebencode.AppendValue
+// would. On error dst is returned as it was.
+func (v Query) AppendBencode(dst []byte) ([]byte, error) {
+	start := len(dst)
+	var err error
+	dst = append(dst, 'd')
+	dst = append(dst, "1:a"...)
+	if dst, err = v.Args.AppendBencode(dst); err != nil {
+		return dst[:start], err
+	}
+	dst = append(dst, "1:q"...)
+	dst = decodebencode.AppendString(dst, v.Method)
+	dst = append(dst, "1:t"...)
+	dst = decodebencode.AppendString(dst, v.TransactionID)
+	if len(v.Version) != 0 {
+		dst = append(dst, "1:v"...)
+		dst = decodebencode.AppendBytes(dst, v.Version)
+	}
+	dst = append(dst, "1:y"...)
+	dst = decodebencode.AppendString(dst, v.Type)
+	dst = append(dst, 'e')
+	return dst, nil
+}
+
+// MarshalBencode implements decodebencode.Marshaler.
+func (v Query) MarshalBencode() ([]byte, error) {
+	return v.AppendBencode(nil)
+}
+
+// UnmarshalBencode implements decodebencode.Unmarshaler.
+func (v *Query) UnmarshalBencode(data []byte) error {
+	t := decodebencode.NewTokenizer(data)
+	tok, err := t.Next()
+	if err == io.EOF {
+		return errors.New("bencode: cannot unmarshal empty input")
+	}
+	if err != nil {
+		return err
+	}
+	if err := v.unmarshalBencodeToken(t, tok); err != nil {
+		return err
+	}
+	if t.Offset() != len(data) {
+		return fmt.Errorf("bencode: unexpected data after the value on index %d", t.Offset())
+	}
+	return nil
+}
+
+// decodes the dict tok starts, tok being the last token t returned
+func (v *Query) unmarshalBencodeToken(t *decodebencode.Tokenizer, tok decodebencode.Token) error {
+	if tok.Kind != decodebencode.TokenDictStart {
+		return bencodegenTypeError(tok, reflect.TypeFor[Query]())
+	}
+	for {
+		key, err := t.Next()
+		if err != nil {
+			return err
+		}
+		if key.Kind == decodebencode.TokenEnd {
+			break
+		}
+		if key.Kind != decodebencode.TokenString {
+			return fmt.Errorf("bencode: %v on index %d cannot be a dict key", key.Kind, key.Offset)
+		}
+		tok, err = t.Next()
+		if err != nil {
+			return err
+		}
+		if tok.Kind == decodebencode.TokenEnd {
+			return fmt.Errorf("bencode: dict key %q on index %d has no value", key.Value, key.Offset)
+		}
+		switch string(key.Value) {
+		case "a":
+			if err := v.Args.unmarshalBencodeToken(t, tok); err != nil {
+				return err
+			}
+		case "q":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[string]())
+			}
+			v.Method = string(tok.Value)
+		case "t":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[string]())
+			}
+			v.TransactionID = string(tok.Value)
+		case "v":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[[]byte]())
+			}
+			v.Version = append([]byte{}, tok.Value...)
+		case "y":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[string]())
+			}
+			v.Type = string(tok.Value)
+		default:
+			if _, err := t.SkipValue(tok); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AppendBencode appends bencode of v to dst, as decodebencode.AppendValue
+// would. On error dst is returned as it was.
+func (v QueryArgs) AppendBencode(dst []byte) ([]byte, error) {
+	start := len(dst)
+	var err error
+	dst = append(dst, 'd')
+	dst = append(dst, "2:id"...)
+	dst = decodebencode.AppendBytes(dst, v.ID[:])
+	if v.ImpliedPort != 0 {
+		dst = append(dst, "12:implied_port"...)
+		dst = decodebencode.AppendInt(dst, int64(v.ImpliedPort))
+	}
+	if v.Port != 0 {
+		dst = append(dst, "4:port"...)
+		dst = decodebencode.AppendUint(dst, uint64(v.Port))
+	}
+	if !bencodegenIsEmpty(reflect.ValueOf(&v.Target).Elem()) {
+		dst = append(dst, "6:target"...)
+		if dst, err = decodebencode.AppendValue(dst, &v.Target); err != nil {
+			return dst[:start], err
+		}
+	}
+	if len(v.Token) != 0 {
+		dst = append(dst, "5:token"...)
+		dst = decodebencode.AppendBytes(dst, v.Token)
+	}
+	if len(v.Want) != 0 {
+		dst = append(dst, "4:want"...)
+		dst = append(dst, 'l')
+		for _, e1 := range v.Want {
+			dst = decodebencode.AppendString(dst, e1)
+		}
+		dst = append(dst, 'e')
+	}
+	dst = append(dst, 'e')
+	return dst, nil
+}
+
+// MarshalBencode implements decodebencode.Marshaler.
+func (v QueryArgs) MarshalBencode() ([]byte, error) {
+	return v.AppendBencode(nil)
+}
+
+// UnmarshalBencode implements decodebencode.Unmarshaler.
+func (v *QueryArgs) UnmarshalBencode(data []byte) error {
+	t := decodebencode.NewTokenizer(data)
+	tok, err := t.Next()
+	if err == io.EOF {
+		return errors.New("bencode: cannot unmarshal empty input")
+	}
+	if err != nil {
+		return err
+	}
+	if err := v.unmarshalBencodeToken(t, tok); err != nil {
+		return err
+	}
+	if t.Offset() != len(data) {
+		return fmt.Errorf("bencode: unexpected data after the value on index %d", t.Offset())
+	}
+	return nil
+}
+
+// decodes the dict tok starts, tok being the last token t returned
+func (v *QueryArgs) unmarshalBencodeToken(t *decodebencode.Tokenizer, tok decodebencode.Token) error {
+	if tok.Kind != decodebencode.TokenDictStart {
+		return bencodegenTypeError(tok, reflect.TypeFor[QueryArgs]())
+	}
+	for {
+		key, err := t.Next()
+		if err != nil {
+			return err
+		}
+		if key.Kind == decodebencode.TokenEnd {
+			break
+		}
+		if key.Kind != decodebencode.TokenString {
+			return fmt.Errorf("bencode: %v on index %d cannot be a dict key", key.Kind, key.Offset)
+		}
+		tok, err = t.Next()
+		if err != nil {
+			return err
+		}
+		if tok.Kind == decodebencode.TokenEnd {
+			return fmt.Errorf("bencode: dict key %q on index %d has no value", key.Value, key.Offset)
+		}
+		switch string(key.Value) {
+		case "id":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[[20]byte]())
+			}
+			if len(tok.Value) != len(v.ID) {
+				return bencodegenTypeError(tok, reflect.TypeFor[[20]byte]())
+			}
+			copy(v.ID[:], tok.Value)
+		case "implied_port":
+			if tok.Kind != decodebencode.TokenInt {
+				return bencodegenTypeError(tok, reflect.TypeFor[int8]())
+			}
+			n1, err := tok.Int()
+			if err != nil {
+				return err
+			}
+			if int(int8(n1)) != n1 {
+				return bencodegenTypeError(tok, reflect.TypeFor[int8]())
+			}
+			v.ImpliedPort = int8(n1)
+		case "port":
+			if tok.Kind != decodebencode.TokenInt {
+				return bencodegenTypeError(tok, reflect.TypeFor[uint16]())
+			}
+			n1, err := tok.Int()
+			if err != nil {
+				return err
+			}
+			if n1 < 0 || int(uint16(n1)) != n1 {
+				return bencodegenTypeError(tok, reflect.TypeFor[uint16]())
+			}
+			v.Port = uint16(n1)
+		case "target":
+			raw1, err := t.SkipValue(tok)
+			if err != nil {
+				return err
+			}
+			if err := decodebencode.Unmarshal(raw1, &v.Target); err != nil {
+				return err
+			}
+		case "token":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[[]byte]())
+			}
+			v.Token = append([]byte{}, tok.Value...)
+		case "want":
+			if tok.Kind != decodebencode.TokenListStart {
+				return bencodegenTypeError(tok, reflect.TypeFor[[]string]())
+			}
+			if v.Want == nil {
+				v.Want = []string{}
+			} else {
+				v.Want = v.Want[:0]
+			}
+			for {
+				tok1, err := t.Next()
+				if err != nil {
+					return err
+				}
+				if tok1.Kind == decodebencode.TokenEnd {
+					break
+				}
+				var e1 string
+				if tok1.Kind != decodebencode.TokenString {
+					return bencodegenTypeError(tok1, reflect.TypeFor[string]())
+				}
+				e1 = string(tok1.Value)
+				v.Want = append(v.Want, e1)
+			}
+		default:
+			if _, err := t.SkipValue(tok); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AppendBencode appends bencode of v to dst, as decodebencode.AppendValue
+// would. On error dst is returned as it was.
+func (v Tracker) AppendBencode(dst []byte) ([]byte, error) {
+	dst = append(dst, 'd')
+	dst = append(dst, "5:tiers"...)
+	dst = append(dst, 'l')
+	for _, e1 := range v.Tiers {
+		dst = append(dst, 'l')
+		for _, e2 := range e1 {
+			dst = decodebencode.AppendString(dst, e2)
+		}
+		dst = append(dst, 'e')
+	}
+	dst = append(dst, 'e')
+	dst = append(dst, "3:url"...)
+	dst = decodebencode.AppendString(dst, v.URL)
+	dst = append(dst, 'e')
+	return dst, nil
+}
+
+// MarshalBencode implements decodebencode.Marshaler.
+func (v Tracker) MarshalBencode() ([]byte, error) {
+	return v.AppendBencode(nil)
+}
+
+// UnmarshalBencode implements decodebencode.Unmarshaler.
+func (v *Tracker) UnmarshalBencode(data []byte) error {
+	t := decodebencode.NewTokenizer(data)
+	tok, err := t.Next()
+	if err == io.EOF {
+		return errors.New("bencode: cannot unmarshal empty input")
+	}
+	if err != nil {
+		return err
+	}
+	if err := v.unmarshalBencodeToken(t, tok); err != nil {
+		return err
+	}
+	if t.Offset() != len(data) {
+		return fmt.Errorf("bencode: unexpected data after the value on index %d", t.Offset())
+	}
+	return nil
+}
+
+// decodes the dict tok starts, tok being the last token t returned
+func (v *Tracker) unmarshalBencodeToken(t *decodebencode.Tokenizer, tok decodebencode.Token) error {
+	if tok.Kind != decodebencode.TokenDictStart {
+		return bencodegenTypeError(tok, reflect.TypeFor[Tracker]())
+	}
+	for {
+		key, err := t.Next()
+		if err != nil {
+			return err
+		}
+		if key.Kind == decodebencode.TokenEnd {
+			break
+		}
+		if key.Kind != decodebencode.TokenString {
+			return fmt.Errorf("bencode: %v on index %d cannot be a dict key", key.Kind, key.Offset)
+		}
+		tok, err = t.Next()
+		if err != nil {
+			return err
+		}
+		if tok.Kind == decodebencode.TokenEnd {
+			return fmt.Errorf("bencode: dict key %q on index %d has no value", key.Value, key.Offset)
+		}
+		switch string(key.Value) {
+		case "tiers":
+			if tok.Kind != decodebencode.TokenListStart {
+				return bencodegenTypeError(tok, reflect.TypeFor[[][]string]())
+			}
+			if v.Tiers == nil {
+				v.Tiers = [][]string{}
+			} else {
+				v.Tiers = v.Tiers[:0]
+			}
+			for {
+				tok1, err := t.Next()
+				if err != nil {
+					return err
+				}
+				if tok1.Kind == decodebencode.TokenEnd {
+					break
+				}
+				var e1 []string
+				if tok1.Kind != decodebencode.TokenListStart {
+					return bencodegenTypeError(tok1, reflect.TypeFor[[]string]())
+				}
+				if e1 == nil {
+					e1 = []string{}
+				} else {
+					e1 = e1[:0]
+				}
+				for {
+					tok2, err := t.Next()
+					if err != nil {
+						return err
+					}
+					if tok2.Kind == decodebencode.TokenEnd {
+						break
+					}
+					var e2 string
+					if tok2.Kind != decodebencode.TokenString {
+						return bencodegenTypeError(tok2, reflect.TypeFor[string]())
+					}
+					e2 = string(tok2.Value)
+					e1 = append(e1, e2)
+				}
+				v.Tiers = append(v.Tiers, e1)
+			}
+		case "url":
+			if tok.Kind != decodebencode.TokenString {
+				return bencodegenTypeError(tok, reflect.TypeFor[string]())
+			}
+			v.URL = string(tok.Value)
+		default:
+			if _, err := t.SkipValue(tok); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func bencodegenTypeError(tok decodebencode.Token, t reflect.Type) error {
+	value := tok.Kind.String()
+	switch tok.Kind {
+	case decodebencode.TokenInt:
+		value = "integer " + string(tok.Value)
+	case decodebencode.TokenListStart:
+		value = "list"
+	case decodebencode.TokenDictStart:
+		value = "dict"
+	}
+	return &decodebencode.UnmarshalTypeError{Value: value, Type: t}
+}
+
+// tells whether omitempty leaves v out, as Marshal does
+func bencodegenIsEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
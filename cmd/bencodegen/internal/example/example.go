@@ -0,0 +1,70 @@
+// Package example holds KRPC and tracker messages with bencodegen generated
+// methods, it is there to test the generated code against Marshal and
+// Unmarshal.
+package example
+
+import "time"
+
+//go:generate go run github.com/jabakot/decode-bencode/cmd/bencodegen
+
+// KRPC query
+//
+//bencode:generate
+type Query struct {
+	TransactionID string    `bencode:"t"`
+	Type          string    `bencode:"y"`
+	Method        string    `bencode:"q"`
+	Args          QueryArgs `bencode:"a"`
+	Version       []byte    `bencode:"v,omitempty"`
+}
+
+//bencode:generate
+type QueryArgs struct {
+	ID          [20]byte  `bencode:"id"`
+	Target      *[20]byte `bencode:"target,omitempty"`
+	Port        uint16    `bencode:"port,omitempty"`
+	ImpliedPort int8      `bencode:"implied_port,omitempty"`
+	Token       []byte    `bencode:"token,omitempty"`
+	Want        []string  `bencode:"want,omitempty"`
+}
+
+type (
+	// Tracker response to an announce
+	//
+	//bencode:generate
+	AnnounceResponse struct {
+		Interval      int                 `bencode:"interval,required"`
+		MinInterval   *int64              `bencode:"min interval,omitempty"`
+		Complete      int64               `bencode:"complete"`
+		Incomplete    uint32              `bencode:"incomplete"`
+		FailureReason string              `bencode:"failure reason,omitempty"`
+		Peers         []Peer              `bencode:"peers"`
+		Peers6        []byte              `bencode:"peers6,omitempty"`
+		Extra         map[string]any      `bencode:"extra,omitempty"`
+		Stats         map[string][]uint8  `bencode:"stats,omitempty"`
+		Updated       time.Time           `bencode:"updated"`
+		Tracker       *Tracker            `bencode:"tracker,omitempty"`
+		Mirrors       map[string]*Tracker `bencode:"mirrors,omitempty"`
+		Version       Version             `bencode:"version"`
+		Skipped       string              `bencode:"-"`
+		internal      string
+	}
+
+	//bencode:generate
+	Peer struct {
+		ID   []byte `bencode:"peer id"`
+		IP   string `bencode:"ip"`
+		Port uint16 `bencode:"port"`
+	}
+)
+
+//bencode:generate
+type Tracker struct {
+	URL   string     `bencode:"url"`
+	Tiers [][]string `bencode:"tiers"`
+}
+
+// not picked, methods of AnnounceResponse leave it to Marshal
+type Version struct {
+	Major, Minor int
+}
@@ -0,0 +1,381 @@
+package example_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+	"github.com/jabakot/decode-bencode/cmd/bencodegen/internal/example"
+)
+
+// Same fields as the generated types but no methods, Marshal and Unmarshal
+// handle them with reflection all the way down.
+type (
+	reflectQueryArgs example.QueryArgs
+	reflectPeer      example.Peer
+	reflectTracker   example.Tracker
+)
+
+type reflectQuery struct {
+	TransactionID string           `bencode:"t"`
+	Type          string           `bencode:"y"`
+	Method        string           `bencode:"q"`
+	Args          reflectQueryArgs `bencode:"a"`
+	Version       []byte           `bencode:"v,omitempty"`
+}
+
+type reflectAnnounce struct {
+	Interval      int                        `bencode:"interval,required"`
+	MinInterval   *int64                     `bencode:"min interval,omitempty"`
+	Complete      int64                      `bencode:"complete"`
+	Incomplete    uint32                     `bencode:"incomplete"`
+	FailureReason string                     `bencode:"failure reason,omitempty"`
+	Peers         []reflectPeer              `bencode:"peers"`
+	Peers6        []byte                     `bencode:"peers6,omitempty"`
+	Extra         map[string]any             `bencode:"extra,omitempty"`
+	Stats         map[string][]uint8         `bencode:"stats,omitempty"`
+	Updated       time.Time                  `bencode:"updated"`
+	Tracker       *reflectTracker            `bencode:"tracker,omitempty"`
+	Mirrors       map[string]*reflectTracker `bencode:"mirrors,omitempty"`
+	Version       example.Version            `bencode:"version"`
+}
+
+func toReflectQuery(q example.Query) reflectQuery {
+	return reflectQuery{
+		TransactionID: q.TransactionID,
+		Type:          q.Type,
+		Method:        q.Method,
+		Args:          reflectQueryArgs(q.Args),
+		Version:       q.Version,
+	}
+}
+
+func fromReflectQuery(q reflectQuery) example.Query {
+	return example.Query{
+		TransactionID: q.TransactionID,
+		Type:          q.Type,
+		Method:        q.Method,
+		Args:          example.QueryArgs(q.Args),
+		Version:       q.Version,
+	}
+}
+
+func toReflectAnnounce(a example.AnnounceResponse) reflectAnnounce {
+	r := reflectAnnounce{
+		Interval:      a.Interval,
+		MinInterval:   a.MinInterval,
+		Complete:      a.Complete,
+		Incomplete:    a.Incomplete,
+		FailureReason: a.FailureReason,
+		Peers6:        a.Peers6,
+		Extra:         a.Extra,
+		Stats:         a.Stats,
+		Updated:       a.Updated,
+		Tracker:       (*reflectTracker)(a.Tracker),
+		Version:       a.Version,
+	}
+	if a.Peers != nil {
+		r.Peers = make([]reflectPeer, len(a.Peers))
+		for i, peer := range a.Peers {
+			r.Peers[i] = reflectPeer(peer)
+		}
+	}
+	if a.Mirrors != nil {
+		r.Mirrors = make(map[string]*reflectTracker, len(a.Mirrors))
+		for key, tracker := range a.Mirrors {
+			r.Mirrors[key] = (*reflectTracker)(tracker)
+		}
+	}
+	return r
+}
+
+func fromReflectAnnounce(r reflectAnnounce) example.AnnounceResponse {
+	a := example.AnnounceResponse{
+		Interval:      r.Interval,
+		MinInterval:   r.MinInterval,
+		Complete:      r.Complete,
+		Incomplete:    r.Incomplete,
+		FailureReason: r.FailureReason,
+		Peers6:        r.Peers6,
+		Extra:         r.Extra,
+		Stats:         r.Stats,
+		Updated:       r.Updated,
+		Tracker:       (*example.Tracker)(r.Tracker),
+		Version:       r.Version,
+	}
+	if r.Peers != nil {
+		a.Peers = make([]example.Peer, len(r.Peers))
+		for i, peer := range r.Peers {
+			a.Peers[i] = example.Peer(peer)
+		}
+	}
+	if r.Mirrors != nil {
+		a.Mirrors = make(map[string]*example.Tracker, len(r.Mirrors))
+		for key, tracker := range r.Mirrors {
+			a.Mirrors[key] = (*example.Tracker)(tracker)
+		}
+	}
+	return a
+}
+
+var target = [20]byte{19: 1}
+
+func sampleAnnounce() example.AnnounceResponse {
+	minInterval := int64(60)
+
+	return example.AnnounceResponse{
+		Interval:    1800,
+		MinInterval: &minInterval,
+		Complete:    -1,
+		Incomplete:  4000000000,
+		Peers: []example.Peer{
+			{ID: []byte("-qB4650-\x00\xff\x01\x02abcdefgh"), IP: "10.0.0.1", Port: 6881},
+			{ID: []byte{}, IP: "::1", Port: 0},
+		},
+		Peers6:  []byte("\x00\x01\x02"),
+		Extra:   map[string]any{"b": 1, "a": []any{"x", 2}, "c": map[string]any{}},
+		Stats:   map[string][]uint8{"z": {1}, "a": {}},
+		Updated: time.Unix(1700000000, 0).UTC(),
+		Tracker: &example.Tracker{URL: "udp://tracker", Tiers: [][]string{{"a", "b"}, {}}},
+		Mirrors: map[string]*example.Tracker{"eu": {URL: "http://eu", Tiers: [][]string{}}},
+		Version: example.Version{Major: 1, Minor: 2},
+	}
+}
+
+func TestGeneratedMarshalMatchesReflection(t *testing.T) {
+	type TestCase struct {
+		name       string
+		generated  decodebencode.Marshaler
+		reflection any
+	}
+
+	announce := sampleAnnounce()
+	query := example.Query{
+		TransactionID: "aa",
+		Type:          "q",
+		Method:        "get_peers",
+		Args:          example.QueryArgs{ID: [20]byte{0: 0xff}, Target: &target, Port: 6881, ImpliedPort: -1, Want: []string{"n4", "n6"}},
+		Version:       []byte("LT\x01\x02"),
+	}
+	failure := example.AnnounceResponse{FailureReason: "unregistered torrent"}
+	tracker := example.Tracker{}
+
+	testCases := []TestCase{
+		{name: "announce", generated: announce, reflection: toReflectAnnounce(announce)},
+		{name: "query", generated: query, reflection: toReflectQuery(query)},
+		{name: "empty query", generated: example.Query{}, reflection: reflectQuery{}},
+		{name: "failure", generated: failure, reflection: toReflectAnnounce(failure)},
+		{name: "nil slices", generated: tracker, reflection: reflectTracker(tracker)},
+		{name: "query args", generated: query.Args, reflection: reflectQueryArgs(query.Args)},
+		{name: "peer", generated: announce.Peers[0], reflection: reflectPeer(announce.Peers[0])},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			generated, err := tc.generated.MarshalBencode()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected, err := decodebencode.Marshal(tc.reflection)
+			if err != nil {
+				t.Fatalf("Unexpected reflection error: %v", err)
+			}
+
+			if !bytes.Equal(generated, expected) {
+				t.Errorf("Expected %q, got %q", expected, generated)
+			}
+		})
+	}
+}
+
+func TestGeneratedUnmarshalMatchesReflection(t *testing.T) {
+	announce, err := sampleAnnounce().MarshalBencode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	inputs := map[string]string{
+		"announce":       string(announce),
+		"unsorted keys":  "d5:peersld4:porti1e2:ip1:xee8:intervali5ee",
+		"unknown keys":   "d7:unknownld1:xi1eee8:intervali5e5:extrad1:ali1eeee",
+		"duplicate keys": "d8:intervali5e8:intervali6e5:peerslee",
+		"plus sign":      "d8:intervali+5e10:incompletei007ee",
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			var generated example.AnnounceResponse
+			if err := generated.UnmarshalBencode([]byte(input)); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var expected reflectAnnounce
+			if err := decodebencode.Unmarshal([]byte(input), &expected); err != nil {
+				t.Fatalf("Unexpected reflection error: %v", err)
+			}
+
+			if !reflect.DeepEqual(generated, fromReflectAnnounce(expected)) {
+				t.Errorf("Expected %+v, got %+v", expected, generated)
+			}
+		})
+	}
+
+	t.Run("query", func(t *testing.T) {
+		input := "d1:ad2:id20:\xff\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x0012:implied_porti-1e4:porti6881e6:target20:\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x014:wantl2:n4ee1:q9:get_peers1:t2:aa1:y1:qe"
+
+		var generated example.Query
+		if err := generated.UnmarshalBencode([]byte(input)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var expected reflectQuery
+		if err := decodebencode.Unmarshal([]byte(input), &expected); err != nil {
+			t.Fatalf("Unexpected reflection error: %v", err)
+		}
+
+		if !reflect.DeepEqual(generated, fromReflectQuery(expected)) {
+			t.Errorf("Expected %+v, got %+v", expected, generated)
+		}
+	})
+}
+
+func TestGeneratedUnmarshalErrors(t *testing.T) {
+	type TestCase struct {
+		name  string
+		input string
+		check func(error) bool
+	}
+
+	isTypeError := func(err error) bool {
+		var typeError *decodebencode.UnmarshalTypeError
+		return errors.As(err, &typeError)
+	}
+
+	testCases := []TestCase{
+		{name: "empty input", input: "", check: func(err error) bool { return err != nil }},
+		{name: "not a dict", input: "li1ee", check: isTypeError},
+		{name: "string for integer", input: "d8:interval1:5e", check: isTypeError},
+		{name: "uint32 overflow", input: "d8:intervali1e10:incompletei4294967296ee", check: isTypeError},
+		{name: "negative uint", input: "d8:intervali1e10:incompletei-1ee", check: isTypeError},
+		{name: "wrong peer", input: "d8:intervali1e5:peersli1eee", check: isTypeError},
+		{
+			name:  "missing required key",
+			input: "d8:completei1ee",
+			check: func(err error) bool {
+				var requiredError *decodebencode.RequiredKeyError
+				return errors.As(err, &requiredError) && requiredError.Key == "interval"
+			},
+		},
+		{name: "integer key", input: "di1ei2ee", check: func(err error) bool { return err != nil }},
+		{name: "key without value", input: "d8:intervale", check: func(err error) bool { return err != nil }},
+		{name: "unclosed dict", input: "d8:intervali1e", check: func(err error) bool { return err != nil }},
+		{name: "trailing data", input: "d8:intervali1eei1e", check: func(err error) bool { return err != nil }},
+		{name: "wrong fallback value", input: "d8:intervali1e7:updated1:xe", check: isTypeError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var generated example.AnnounceResponse
+			err := generated.UnmarshalBencode([]byte(tc.input))
+			if !tc.check(err) {
+				t.Errorf("Unexpected error %v", err)
+			}
+
+			var expected reflectAnnounce
+			if decodebencode.Unmarshal([]byte(tc.input), &expected) == nil {
+				t.Errorf("Expected reflection to fail too")
+			}
+		})
+	}
+}
+
+func TestGeneratedMarshalNilPointer(t *testing.T) {
+	announce := example.AnnounceResponse{Mirrors: map[string]*example.Tracker{"eu": nil}}
+
+	_, err := announce.MarshalBencode()
+	var valueError *decodebencode.UnsupportedValueError
+	if !errors.As(err, &valueError) {
+		t.Errorf("Expected *UnsupportedValueError, got %v", err)
+	}
+
+	if _, err := decodebencode.Marshal(toReflectAnnounce(announce)); err == nil {
+		t.Errorf("Expected reflection to fail too")
+	}
+
+	dst := []byte("prefix")
+	if dst, err := announce.AppendBencode(dst); err == nil || string(dst) != "prefix" {
+		t.Errorf("Expected dst to be returned as it was, got %q", dst)
+	}
+}
+
+func TestUnmarshalUsesGeneratedMethods(t *testing.T) {
+	announce := sampleAnnounce()
+	data, err := decodebencode.Marshal(announce)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var output example.AnnounceResponse
+	if err := decodebencode.Unmarshal(data, &output); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, announce) {
+		t.Errorf("Expected %+v, got %+v", announce, output)
+	}
+
+	var wrapped struct {
+		Trackers []example.Tracker `bencode:"trackers"`
+	}
+	if err := decodebencode.Unmarshal([]byte("d8:trackersld3:url1:xeee"), &wrapped); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(wrapped.Trackers) != 1 || wrapped.Trackers[0].URL != "x" {
+		t.Errorf("Unexpected %+v", wrapped)
+	}
+}
+
+const krpcQuery = "d1:ad2:id20:abcdefghij01234567896:target20:mnopqrstuvwxyz123456e1:q9:find_node1:t2:aa1:y1:qe"
+
+func BenchmarkKRPCQuery(b *testing.B) {
+	var query example.Query
+	if err := query.UnmarshalBencode([]byte(krpcQuery)); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("generated marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := make([]byte, 0, 256)
+		for b.Loop() {
+			buf, _ = query.AppendBencode(buf[:0])
+		}
+	})
+
+	b.Run("reflection marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		plain := toReflectQuery(query)
+		for b.Loop() {
+			decodebencode.Marshal(plain)
+		}
+	})
+
+	b.Run("generated unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var q example.Query
+			q.UnmarshalBencode([]byte(krpcQuery))
+		}
+	})
+
+	b.Run("reflection unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var q reflectQuery
+			decodebencode.Unmarshal([]byte(krpcQuery), &q)
+		}
+	})
+}
@@ -0,0 +1,66 @@
+// Command bencodegen writes MarshalBencode, AppendBencode and UnmarshalBencode
+// methods for struct types, so that encoding and decoding them takes no
+// reflection. Struct types are picked with a directive in their doc comment:
+//
+//	//bencode:generate
+//	type Ping struct {
+//		T string `bencode:"t"`
+//		Y string `bencode:"y"`
+//		A PingArgs `bencode:"a"`
+//	}
+//
+// and the package gets
+//
+//	//go:generate go run github.com/jabakot/decode-bencode/cmd/bencodegen
+//
+// Methods of all picked types of the package are written to one file,
+// bencode_gen.go by default. Field tags mean what they mean for Marshal and
+// Unmarshal, except that inline and embedded fields are not supported.
+// Fields of strings, integers, byte slices and arrays, slices, string keyed
+// maps and picked struct types, or pointers to them, are encoded by the
+// generated code, fields of any other type are left to Marshal and Unmarshal.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	output := flag.String("output", "bencode_gen.go", "file to write, relative to the package directory")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: bencodegen [-output file] [package directory]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if flag.NArg() == 1 {
+		dir = flag.Arg(0)
+	}
+
+	if err := run(dir, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "bencodegen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, output string) error {
+	pkg, err := parsePackage(dir, output)
+	if err != nil {
+		return err
+	}
+
+	src, err := generate(pkg)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, output), src, 0o644)
+}
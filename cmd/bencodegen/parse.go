@@ -0,0 +1,224 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// doc comment line that picks a struct type
+const directive = "//bencode:generate"
+
+type genPackage struct {
+	name  string
+	types []*genStruct
+	// names of the picked types
+	picked map[string]bool
+}
+
+type genStruct struct {
+	name   string
+	fields []*genField
+}
+
+type genField struct {
+	// Go name, used to access the field
+	goName string
+	// dict key
+	key       string
+	typ       ast.Expr
+	tagged    bool
+	omitEmpty bool
+	required  bool
+}
+
+// Parses the non-test Go files of dir, except output, and collects the picked
+// struct types.
+func parsePackage(dir, output string) (*genPackage, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	pkg := &genPackage{picked: map[string]bool{}}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == output {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+
+		if pkg.name != "" && pkg.name != file.Name.Name {
+			return nil, fmt.Errorf("%s: found packages %s and %s", dir, pkg.name, file.Name.Name)
+		}
+		pkg.name = file.Name.Name
+
+		if err := collectTypes(pkg, file); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	if len(pkg.types) == 0 {
+		return nil, fmt.Errorf("%s: no struct type has a %s comment", dir, directive)
+	}
+
+	slices.SortFunc(pkg.types, func(a, b *genStruct) int {
+		return cmp.Compare(a.name, b.name)
+	})
+
+	return pkg, nil
+}
+
+func hasDirective(groups ...*ast.CommentGroup) bool {
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, comment := range group.List {
+			if strings.TrimSpace(comment.Text) == directive {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func collectTypes(pkg *genPackage, file *ast.File) error {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+
+			// the comment of a lone type is on the declaration
+			if !hasDirective(ts.Doc) && !(len(gen.Specs) == 1 && hasDirective(gen.Doc)) {
+				continue
+			}
+
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || ts.TypeParams != nil {
+				return fmt.Errorf("%s: only struct types without type parameters can be generated", ts.Name.Name)
+			}
+
+			fields, err := collectFields(ts.Name.Name, st)
+			if err != nil {
+				return err
+			}
+
+			pkg.types = append(pkg.types, &genStruct{name: ts.Name.Name, fields: fields})
+			pkg.picked[ts.Name.Name] = true
+		}
+	}
+
+	return nil
+}
+
+// Fields in the order of their keys, resolved the way Marshal resolves them
+func collectFields(typeName string, st *ast.StructType) ([]*genField, error) {
+	byKey := map[string][]*genField{}
+
+	for _, f := range st.Fields.List {
+		var tag string
+		if f.Tag != nil {
+			unquoted, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", typeName, err)
+			}
+			tag = reflect.StructTag(unquoted).Get("bencode")
+		}
+		if tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if slices.Contains(strings.Split(options, ","), "inline") {
+			return nil, fmt.Errorf("%s: inline fields are not supported", typeName)
+		}
+
+		names := f.Names
+		if len(names) == 0 {
+			embedded := embeddedName(f.Type)
+			if name == "" {
+				return nil, fmt.Errorf("%s: embedded field %s is not supported, name its key in the tag", typeName, embedded)
+			}
+			names = []*ast.Ident{ast.NewIdent(embedded)}
+		}
+
+		for _, ident := range names {
+			if !ast.IsExported(ident.Name) {
+				continue
+			}
+
+			field := &genField{
+				goName:    ident.Name,
+				key:       cmp.Or(name, ident.Name),
+				typ:       f.Type,
+				tagged:    name != "",
+				omitEmpty: slices.Contains(strings.Split(options, ","), "omitempty"),
+				required:  slices.Contains(strings.Split(options, ","), "required"),
+			}
+			byKey[field.key] = append(byKey[field.key], field)
+		}
+	}
+
+	var fields []*genField
+	for _, candidates := range byKey {
+		if field, ok := dominantField(candidates); ok {
+			fields = append(fields, field)
+		}
+	}
+
+	slices.SortFunc(fields, func(a, b *genField) int {
+		return cmp.Compare(a.key, b.key)
+	})
+
+	return fields, nil
+}
+
+// the one tagged field among fields with the same key, or the only field
+func dominantField(fields []*genField) (*genField, bool) {
+	if len(fields) == 1 {
+		return fields[0], true
+	}
+
+	var tagged []*genField
+	for _, f := range fields {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+
+	if len(tagged) != 1 {
+		return nil, false
+	}
+	return tagged[0], true
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	}
+	return fmt.Sprint(expr)
+}
@@ -0,0 +1,200 @@
+package decodebencode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Types that have no bencode form of their own can be given one with
+// RegisterConverter. These are registered by default:
+//
+//	time.Time       integer, Unix seconds, decoded in UTC
+//	time.Duration   integer, whole seconds
+//	net.IP          string of 4 or 16 bytes, IPv4 addresses always take 4
+//	netip.Addr      string of 4 or 16 bytes
+//	netip.AddrPort  string of 6 or 18 bytes, the address followed by the big
+//	                endian port, as in compact peer lists (BEP-23, BEP-7)
+//
+// Zero net.IP, netip.Addr and netip.AddrPort values are encoded as empty
+// strings. Converters are used by Marshal and Unmarshal before anything else
+// but Marshaler.
+
+// Returned when a registered converter fails
+type ConverterError struct {
+	Type reflect.Type
+	Err  error
+	Path Path
+}
+
+func (e *ConverterError) Error() string {
+	return "bencode: cannot convert " + e.Type.String() + atPath(e.Path) + ": " + e.Err.Error()
+}
+
+func (e *ConverterError) Unwrap() error {
+	return e.Err
+}
+
+type converter struct {
+	// bencode form of v
+	encode func(v reflect.Value) (any, error)
+	// stores decoded tree in v
+	decode func(tree any, v reflect.Value) error
+}
+
+// reflect.Type -> converter
+var converters sync.Map
+
+// Makes Marshal encode values of type T as whatever encode returns, B being
+// any type Marshal accepts, and Unmarshal decode them by decoding into B first
+// and passing the result to decode. Registering a type again replaces its
+// converter, built-in ones included. Meant to be called from init, before any
+// encoding or decoding.
+func RegisterConverter[T, B any](encode func(T) (B, error), decode func(B) (T, error)) {
+	converters.Store(reflect.TypeFor[T](), converter{
+		encode: func(v reflect.Value) (any, error) {
+			return encode(v.Interface().(T))
+		},
+		decode: func(tree any, v reflect.Value) error {
+			var b B
+			if err := unmarshalValue(tree, reflect.ValueOf(&b).Elem()); err != nil {
+				if typeError, ok := err.(*UnmarshalTypeError); ok {
+					typeError.Type = v.Type()
+				}
+				return err
+			}
+
+			value, err := decode(b)
+			if err != nil {
+				return err
+			}
+
+			v.Set(reflect.ValueOf(value))
+			return nil
+		},
+	})
+}
+
+func converterFor(t reflect.Type) (converter, bool) {
+	c, ok := converters.Load(t)
+	if !ok {
+		return converter{}, false
+	}
+	return c.(converter), true
+}
+
+func (e *encodeState) marshalConverted(v reflect.Value, c converter) error {
+	converted, err := c.encode(v)
+	if err != nil {
+		return &ConverterError{Type: v.Type(), Err: err}
+	}
+
+	return e.marshal(reflect.ValueOf(converted))
+}
+
+func unmarshalConverted(tree any, v reflect.Value, c converter) error {
+	err := c.decode(tree, v)
+
+	switch err.(type) {
+	case nil, *UnmarshalTypeError, *ConverterError:
+		return err
+	}
+
+	return &ConverterError{Type: v.Type(), Err: err}
+}
+
+func init() {
+	RegisterConverter(encodeTime, decodeTime)
+	RegisterConverter(encodeDuration, decodeDuration)
+	RegisterConverter(encodeIP, decodeIP)
+	RegisterConverter(encodeAddr, decodeAddr)
+	RegisterConverter(encodeAddrPort, decodeAddrPort)
+}
+
+func encodeTime(t time.Time) (int64, error) {
+	return t.Unix(), nil
+}
+
+func decodeTime(seconds int64) (time.Time, error) {
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+func encodeDuration(d time.Duration) (int64, error) {
+	return int64(d / time.Second), nil
+}
+
+func decodeDuration(seconds int64) (time.Duration, error) {
+	if seconds > math.MaxInt64/int64(time.Second) || seconds < math.MinInt64/int64(time.Second) {
+		return 0, fmt.Errorf("%d seconds overflow time.Duration", seconds)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func encodeIP(ip net.IP) ([]byte, error) {
+	if len(ip) == 0 {
+		return []byte{}, nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, nil
+	}
+	if len(ip) != net.IPv6len {
+		return nil, fmt.Errorf("IP address of %d bytes", len(ip))
+	}
+	return ip, nil
+}
+
+func decodeIP(b []byte) (net.IP, error) {
+	switch len(b) {
+	case 0:
+		return nil, nil
+	case net.IPv4len, net.IPv6len:
+		return net.IP(b), nil
+	}
+	return nil, fmt.Errorf("IP address of %d bytes, expected %d or %d", len(b), net.IPv4len, net.IPv6len)
+}
+
+func encodeAddr(addr netip.Addr) ([]byte, error) {
+	if !addr.IsValid() {
+		return []byte{}, nil
+	}
+	return addr.AsSlice(), nil
+}
+
+func decodeAddr(b []byte) (netip.Addr, error) {
+	if len(b) == 0 {
+		return netip.Addr{}, nil
+	}
+
+	addr, ok := netip.AddrFromSlice(b)
+	if !ok {
+		return netip.Addr{}, fmt.Errorf("IP address of %d bytes, expected %d or %d", len(b), net.IPv4len, net.IPv6len)
+	}
+	return addr, nil
+}
+
+func encodeAddrPort(addrPort netip.AddrPort) ([]byte, error) {
+	if !addrPort.IsValid() {
+		return []byte{}, nil
+	}
+
+	compact := addrPort.Addr().AsSlice()
+	return binary.BigEndian.AppendUint16(compact, addrPort.Port()), nil
+}
+
+func decodeAddrPort(b []byte) (netip.AddrPort, error) {
+	if len(b) == 0 {
+		return netip.AddrPort{}, nil
+	}
+
+	if len(b) != net.IPv4len+2 && len(b) != net.IPv6len+2 {
+		return netip.AddrPort{}, fmt.Errorf("compact address of %d bytes, expected %d or %d", len(b), net.IPv4len+2, net.IPv6len+2)
+	}
+
+	addr, _ := netip.AddrFromSlice(b[:len(b)-2])
+	return netip.AddrPortFrom(addr, binary.BigEndian.Uint16(b[len(b)-2:])), nil
+}
@@ -0,0 +1,175 @@
+package decodebencode_test
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+type announce struct {
+	Created  time.Time      `bencode:"created"`
+	Interval time.Duration  `bencode:"interval"`
+	IP       net.IP         `bencode:"ip"`
+	External netip.Addr     `bencode:"external"`
+	Peer     netip.AddrPort `bencode:"peer"`
+	Seen     *time.Time     `bencode:"seen,omitempty"`
+}
+
+// temperature kept in tenths of a degree, to test RegisterConverter
+type celsius float64
+
+func init() {
+	decodebencode.RegisterConverter(
+		func(c celsius) (int64, error) {
+			return int64(c * 10), nil
+		},
+		func(tenths int64) (celsius, error) {
+			if tenths < -2732 {
+				return 0, errors.New("below absolute zero")
+			}
+			return celsius(tenths) / 10, nil
+		},
+	)
+}
+
+func TestConvertersMarshal(t *testing.T) {
+	type TestCase struct {
+		name     string
+		input    any
+		expected string
+	}
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	testCases := []TestCase{
+		{name: "time", input: created, expected: "i1704164645e"},
+		{name: "time before 1970", input: time.Date(1969, 12, 31, 23, 59, 0, 0, time.UTC), expected: "i-60e"},
+		{name: "pointer to time", input: &created, expected: "i1704164645e"},
+		{name: "duration", input: 30 * time.Minute, expected: "i1800e"},
+		{name: "duration truncated to seconds", input: 1500 * time.Millisecond, expected: "i1e"},
+		{name: "IPv4", input: net.IPv4(10, 0, 0, 1), expected: "4:\x0a\x00\x00\x01"},
+		{name: "IPv6", input: net.ParseIP("2001:db8::1"), expected: "16: \x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01"},
+		{name: "nil IP", input: net.IP(nil), expected: "0:"},
+		{name: "netip IPv4", input: netip.MustParseAddr("127.0.0.1"), expected: "4:\x7f\x00\x00\x01"},
+		{name: "zero netip address", input: netip.Addr{}, expected: "0:"},
+		{name: "compact IPv4 peer", input: netip.MustParseAddrPort("10.0.0.2:6881"), expected: "6:\x0a\x00\x00\x02\x1a\xe1"},
+		{name: "compact IPv6 peer", input: netip.MustParseAddrPort("[::1]:80"), expected: "18:\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x50"},
+		{name: "list of peers", input: []netip.AddrPort{netip.MustParseAddrPort("1.2.3.4:1")}, expected: "l6:\x01\x02\x03\x04\x00\x01e"},
+		{name: "registered converter", input: celsius(36.6), expected: "i366e"},
+		{
+			name: "struct",
+			input: announce{
+				Created:  created,
+				Interval: time.Hour,
+				IP:       net.IPv4(1, 2, 3, 4),
+				External: netip.MustParseAddr("5.6.7.8"),
+				Peer:     netip.MustParseAddrPort("9.9.9.9:256"),
+			},
+			expected: "d7:createdi1704164645e8:external4:\x05\x06\x07\x088:intervali3600e2:ip4:\x01\x02\x03\x044:peer6:\x09\x09\x09\x09\x01\x00e",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := decodebencode.Marshal(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if string(output) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestConvertersUnmarshal(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	input := announce{
+		Created:  created,
+		Interval: 90 * time.Second,
+		IP:       net.ParseIP("2001:db8::1"),
+		External: netip.MustParseAddr("5.6.7.8"),
+		Peer:     netip.MustParseAddrPort("[2001:db8::2]:6881"),
+		Seen:     &created,
+	}
+
+	data, err := decodebencode.Marshal(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var output announce
+	if err := decodebencode.Unmarshal(data, &output); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, input) {
+		t.Errorf("Expected %+v, got %+v", input, output)
+	}
+
+	var temperature celsius
+	if err := decodebencode.Unmarshal([]byte("i-45e"), &temperature); err != nil || temperature != -4.5 {
+		t.Errorf("Expected -4.5, got %v, %v", temperature, err)
+	}
+}
+
+func TestConvertersUnmarshalErrors(t *testing.T) {
+	type TestCase struct {
+		name      string
+		input     string
+		converter bool
+		path      string
+	}
+
+	testCases := []TestCase{
+		{name: "time from string", input: "d7:created3:nowe", path: "created"},
+		{name: "IP of 5 bytes", input: "d2:ip5:abcdee", converter: true, path: "ip"},
+		{name: "address from integer", input: "d8:externali1ee", path: "external"},
+		{name: "peer of 7 bytes", input: "d4:peer7:abcdefge", converter: true, path: "peer"},
+		{name: "duration overflow", input: "d8:intervali9223372036854775807ee", converter: true, path: "interval"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var output announce
+			err := decodebencode.Unmarshal([]byte(tc.input), &output)
+
+			var converterError *decodebencode.ConverterError
+			var typeError *decodebencode.UnmarshalTypeError
+
+			switch {
+			case tc.converter && errors.As(err, &converterError):
+				if converterError.Path.String() != tc.path {
+					t.Errorf("Expected path %q, got %q", tc.path, converterError.Path)
+				}
+			case !tc.converter && errors.As(err, &typeError):
+				if typeError.Path.String() != tc.path {
+					t.Errorf("Expected path %q, got %q", tc.path, typeError.Path)
+				}
+			default:
+				t.Errorf("Unexpected error %v", err)
+			}
+		})
+	}
+
+	var temperature celsius
+	err := decodebencode.Unmarshal([]byte("i-3000e"), &temperature)
+	if err == nil || err.Error() != "bencode: cannot convert decodebencode_test.celsius: below absolute zero" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestConvertersMarshalError(t *testing.T) {
+	_, err := decodebencode.Marshal(map[string]any{"ip": net.IP{1, 2, 3}})
+
+	var converterError *decodebencode.ConverterError
+	if !errors.As(err, &converterError) || converterError.Path.String() != "ip" {
+		t.Errorf("Expected *ConverterError at `ip`, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package decodebencode
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// pointers, maps and slices nested deeper than that are checked for cycles
+const startDetectingCyclesAfter = 1000
+
+// nesting allowed when EncodeOptions.MaxDepth is not set, Valid rejects deeper
+// output anyway
+const maxEncodeDepth = maxValidDepth
+
+// Returned when a value contains itself, which is noticed once pointers, maps
+// and slices are nested more than 1000 levels deep. Path leads to the value
+// where the cycle was found, it is left out of the message as it is long by
+// nature.
+type CycleError struct {
+	Type reflect.Type
+	Path Path
+}
+
+func (e *CycleError) Error() string {
+	return "bencode: encountered a cycle via " + e.Type.String()
+}
+
+// Returned when lists and dicts are nested deeper than the encoding allows
+type MaxDepthError struct {
+	Depth int
+}
+
+func (e *MaxDepthError) Error() string {
+	return fmt.Sprintf("bencode: exceeded max depth of %d nested lists and dicts", e.Depth)
+}
+
+// identifies a slice, two slices are the same when they start at the same
+// address and have the same length
+type sliceIdentity struct {
+	ptr uintptr
+	len int
+}
+
+// Marks pointer, map or slice v as being encoded. Below
+// startDetectingCyclesAfter levels every one of them is remembered until its
+// encoding is done, meeting one again means a cycle. Returns the key v is
+// remembered under, nil while cycles are not looked for yet, or an error when
+// v is already being encoded. Every successful call must be followed by
+// leaveReference with the returned key.
+func (e *encodeState) enterReference(v reflect.Value) (any, error) {
+	e.ptrLevel++
+	if e.ptrLevel <= startDetectingCyclesAfter {
+		return nil, nil
+	}
+
+	var key any
+	switch v.Kind() {
+	case reflect.Slice:
+		key = sliceIdentity{ptr: uintptr(v.UnsafePointer()), len: v.Len()}
+	default:
+		key = v.UnsafePointer()
+	}
+
+	if _, ok := e.ptrSeen[key]; ok {
+		e.ptrLevel--
+		return nil, &CycleError{Type: v.Type()}
+	}
+
+	if e.ptrSeen == nil {
+		e.ptrSeen = make(map[any]struct{})
+	}
+	e.ptrSeen[key] = struct{}{}
+
+	return key, nil
+}
+
+func (e *encodeState) leaveReference(key any) {
+	if key != nil {
+		delete(e.ptrSeen, key)
+	}
+	e.ptrLevel--
+}
+
+// Opens a list or dict, returns an error when that is one more than
+// EncodeOptions.MaxDepth, or maxEncodeDepth when it is not set
+func (e *encodeState) enterContainer() error {
+	limit := e.opts.MaxDepth
+	if limit <= 0 {
+		limit = maxEncodeDepth
+	}
+
+	if e.depth >= limit {
+		return &MaxDepthError{Depth: limit}
+	}
+
+	e.depth++
+	return nil
+}
+
+func (e *encodeState) leaveContainer() {
+	e.depth--
+}
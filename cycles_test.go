@@ -0,0 +1,122 @@
+package decodebencode_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+type node struct {
+	Name     string  `bencode:"name"`
+	Children []*node `bencode:"children"`
+	Parent   *node   `bencode:"parent,omitempty"`
+}
+
+func TestMarshalCycles(t *testing.T) {
+	selfMap := map[string]any{}
+	selfMap["self"] = selfMap
+
+	throughSlice := map[string]any{}
+	throughSlice["list"] = []any{1, throughSlice}
+
+	selfSlice := make([]any, 1)
+	selfSlice[0] = selfSlice
+
+	root := &node{Name: "root"}
+	root.Children = []*node{{Name: "child", Parent: root}}
+
+	selfPointer := new(any)
+	*selfPointer = selfPointer
+
+	testCases := map[string]any{
+		"map containing itself":       selfMap,
+		"map containing itself twice": map[string]any{"a": selfMap},
+		"cycle through a slice":       throughSlice,
+		"slice containing itself":     selfSlice,
+		"struct pointing to parent":   root,
+		"pointer to itself":           selfPointer,
+	}
+
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodebencode.Marshal(input)
+
+			var cycleError *decodebencode.CycleError
+			if !errors.As(err, &cycleError) {
+				t.Fatalf("Expected *CycleError, got %v", err)
+			}
+			if len(cycleError.Path) == 0 && name != "pointer to itself" {
+				t.Errorf("Expected a path to the cycle")
+			}
+
+			if size := decodebencode.EncodedLen(input); size != -1 {
+				t.Errorf("Expected EncodedLen -1, got %d", size)
+			}
+		})
+	}
+
+	t.Run("encoders", func(t *testing.T) {
+		if _, err := decodebencode.EncodeBencodeDict(selfMap); err == nil {
+			t.Errorf("Expected an error from EncodeBencodeDict")
+		}
+		if _, err := decodebencode.EncodeBencodeList(selfSlice); err == nil {
+			t.Errorf("Expected an error from EncodeBencodeList")
+		}
+		if err := decodebencode.NewEncoder(&bytes.Buffer{}).Encode(throughSlice); err == nil {
+			t.Errorf("Expected an error from Encoder")
+		}
+	})
+}
+
+func TestMarshalSharedValuesAreNotCycles(t *testing.T) {
+	shared := []int{1, 2}
+	input := map[string]any{"a": shared, "b": shared, "c": []any{shared, shared}}
+
+	output, err := decodebencode.Marshal(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "d1:ali1ei2ee1:bli1ei2ee1:clli1ei2eeli1ei2eeee"
+	if string(output) != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestMarshalMaxDepth(t *testing.T) {
+	nest := func(depth int) any {
+		var v any = 1
+		for range depth {
+			v = []any{v}
+		}
+		return v
+	}
+
+	output, err := decodebencode.Marshal(nest(10000))
+	if err != nil {
+		t.Fatalf("Unexpected error at the depth limit: %v", err)
+	}
+	if !decodebencode.Valid(output) {
+		t.Errorf("Output at the depth limit is not valid")
+	}
+
+	_, err = decodebencode.Marshal(nest(10001))
+	var depthError *decodebencode.MaxDepthError
+	if !errors.As(err, &depthError) || depthError.Depth != 10000 {
+		t.Fatalf("Expected *MaxDepthError for 10000, got %v", err)
+	}
+
+	opts := decodebencode.EncodeOptions{MaxDepth: 2}
+	if output, err := opts.Marshal(nest(2)); err != nil || string(output) != "lli1eee" {
+		t.Errorf("Expected %q, got %q, %v", "lli1eee", output, err)
+	}
+
+	_, err = opts.Marshal(map[string]any{"a": map[string]any{"b": []int{}}})
+	expected := "bencode: exceeded max depth of 2 nested lists and dicts"
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+}
@@ -0,0 +1,63 @@
+package decodebencode
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Outcome of decoding one of the DecodeAll inputs
+type DecodeResult struct {
+	Value interface{}
+	Err   error
+}
+
+// Decodes every input concurrently with at most workers goroutines (GOMAXPROCS
+// when workers < 1). Results come back in input order, a failing input doesn't
+// stop the others. Inputs not decoded before ctx is done get ctx.Err() wrapped.
+func DecodeAll(ctx context.Context, inputs [][]byte, workers int) []DecodeResult {
+	results := make([]DecodeResult, len(inputs))
+
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(inputs))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// every worker owns its state and reuses it for all of its inputs
+			state := getDecodeState()
+			defer putDecodeState(state)
+
+			for i := range jobs {
+				state.reset()
+				value, err := decodeInput(ctx, state, inputs[i])
+				results[i] = DecodeResult{Value: value, Err: err}
+			}
+		}()
+	}
+
+	next := 0
+dispatch:
+	for ; next < len(inputs); next++ {
+		select {
+		case jobs <- next:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := next; i < len(inputs); i++ {
+		results[i] = DecodeResult{Err: canceledError(0, ctx.Err())}
+	}
+
+	return results
+}
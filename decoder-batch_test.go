@@ -0,0 +1,71 @@
+package decodebencode_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func TestDecodeAll(t *testing.T) {
+	inputs := make([][]byte, 0, 100)
+	expected := make([]decodebencode.DecodeResult, 0, 100)
+
+	for i := range 100 {
+		if i%10 == 3 {
+			inputs = append(inputs, []byte("li1e"))
+			expected = append(expected, decodebencode.DecodeResult{})
+			continue
+		}
+		inputs = append(inputs, []byte(fmt.Sprintf("d2:idi%de4:listli%de1:xee", i, i)))
+		expected = append(expected, decodebencode.DecodeResult{
+			Value: map[string]interface{}{"id": i, "list": []interface{}{i, "x"}},
+		})
+	}
+
+	for _, workers := range []int{0, 1, 4, 1000} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			results := decodebencode.DecodeAll(context.Background(), inputs, workers)
+
+			if len(results) != len(inputs) {
+				t.Fatalf("Expected %d results, got %d", len(inputs), len(results))
+			}
+
+			for i, result := range results {
+				if i%10 == 3 {
+					if result.Err == nil || result.Value != nil {
+						t.Errorf("Expected error for input %d, got %v", i, result)
+					}
+					continue
+				}
+				if !reflect.DeepEqual(result, expected[i]) {
+					t.Errorf("Expected %v for input %d, got %v", expected[i], i, result)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeAllCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := decodebencode.DecodeAll(ctx, [][]byte{[]byte("i1e"), []byte("i2e")}, 2)
+
+	for i, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("Expected canceled error for input %d, got %v", i, result.Err)
+		}
+	}
+}
+
+func TestDecodeAllEmpty(t *testing.T) {
+	results := decodebencode.DecodeAll(context.Background(), nil, 4)
+
+	if len(results) != 0 {
+		t.Errorf("Expected no results, got %v", results)
+	}
+}
@@ -0,0 +1,133 @@
+package decodebencode
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Single step into a decoded tree, either a dict key or a list index
+type PathSegment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// Location of a value inside a decoded tree, empty path is the root value
+type Path []PathSegment
+
+// Renders path as `info.files[2].path[0]`, keys that cannot be written bare
+// are quoted: `info["name.utf-8"]`, `files["\x00\xff"]`
+func (p Path) String() string {
+	var sb strings.Builder
+
+	for i, segment := range p {
+		switch {
+		case segment.IsIndex:
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Itoa(segment.Index))
+			sb.WriteByte(']')
+		case !isBareKey(segment.Key):
+			sb.WriteByte('[')
+			sb.WriteString(strconv.Quote(segment.Key))
+			sb.WriteByte(']')
+		default:
+			if i > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(segment.Key)
+		}
+	}
+
+	return sb.String()
+}
+
+// binary and control characters are quoted, so paths are safe to print
+func isBareKey(key string) bool {
+	return len(key) > 0 && isPrintable(key) && !strings.ContainsAny(key, `.[]"\`)
+}
+
+// valid UTF-8 without control characters
+func isPrintable(s string) bool {
+	return utf8.ValidString(s) && strings.IndexFunc(s, func(r rune) bool { return !unicode.IsPrint(r) }) < 0
+}
+
+// Converts a decoded value, whatever it returns replaces the value in the tree
+type DecodeHook func(path Path, value interface{}) (interface{}, error)
+
+type decodeHook struct {
+	match func(path Path, value interface{}) bool
+	hook  DecodeHook
+}
+
+// Runs hook for the value found exactly at path, path is written the way
+// Path.String renders it, e.g. `creation date` or `info.files[0].length`
+func (d *Decoder) OnPath(path string, hook DecodeHook) {
+	d.OnMatch(func(p Path, _ interface{}) bool {
+		return p.String() == path
+	}, hook)
+}
+
+// Runs hook for every value match returns true for. Hooks run in the order
+// they were added, each one gets the value returned by the previous one.
+func (d *Decoder) OnMatch(match func(path Path, value interface{}) bool, hook DecodeHook) {
+	d.hooks = append(d.hooks, decodeHook{match: match, hook: hook})
+}
+
+// Path of the element on top of the stack, false when the element is a dict key
+func (s *decodeState) topPath() (Path, bool) {
+	path := Path{}
+
+	child := len(s.stack) - 1
+	for i := len(s.containers) - 1; i >= 0; i-- {
+		m := s.containers[i]
+		position := child - m - 1
+
+		if IsListSymbol(s.stack[m]) {
+			path = append(path, PathSegment{Index: position, IsIndex: true})
+		} else if position%2 == 0 {
+			// dict keys are not values, they have no path
+			return nil, false
+		} else {
+			key, _ := s.stack[child-1].(string)
+			path = append(path, PathSegment{Key: key})
+		}
+		child = m
+	}
+
+	slices.Reverse(path)
+
+	return path, true
+}
+
+// Pushes finished value to the stack and runs matching hooks on it
+func (s *decodeState) pushValue(v interface{}, offset int) error {
+	s.push(v, offset)
+
+	if len(s.hooks) == 0 {
+		return nil
+	}
+
+	path, ok := s.topPath()
+	if !ok {
+		return nil
+	}
+
+	top := len(s.stack) - 1
+	for _, h := range s.hooks {
+		if !h.match(path, s.stack[top]) {
+			continue
+		}
+
+		converted, err := h.hook(path, s.stack[top])
+		if err != nil {
+			return fmt.Errorf("decode hook failed for `%s` on index %d: %w", path, offset, err)
+		}
+		s.stack[top] = converted
+	}
+
+	return nil
+}
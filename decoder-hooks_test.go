@@ -0,0 +1,118 @@
+package decodebencode_test
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func TestPathString(t *testing.T) {
+	type TestCase struct {
+		name     string
+		input    decodebencode.Path
+		expected string
+	}
+
+	testCases := []TestCase{
+		{name: "root", input: decodebencode.Path{}, expected: ""},
+		{name: "single key", input: decodebencode.Path{{Key: "creation date"}}, expected: "creation date"},
+		{
+			name: "keys and indexes",
+			input: decodebencode.Path{
+				{Key: "info"}, {Key: "files"}, {Index: 2, IsIndex: true}, {Key: "path"}, {Index: 0, IsIndex: true},
+			},
+			expected: "info.files[2].path[0]",
+		},
+		{name: "key with dot is quoted", input: decodebencode.Path{{Key: "info"}, {Key: "name.utf-8"}}, expected: `info["name.utf-8"]`},
+		{name: "empty key is quoted", input: decodebencode.Path{{Key: ""}}, expected: `[""]`},
+		{name: "root list", input: decodebencode.Path{{Index: 1, IsIndex: true}, {Key: "a"}}, expected: "[1].a"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := tc.input.String()
+			if output != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestDecoderHooks(t *testing.T) {
+	// 127.0.0.1:6881 and 10.0.0.2:80 in compact form
+	peers := string([]byte{127, 0, 0, 1, 0x1a, 0xe1, 10, 0, 0, 2, 0, 80})
+	input := fmt.Sprintf("d13:creation datei1700000000e4:infod5:filesld6:lengthi7eeee5:peers%d:%se", len(peers), peers)
+
+	decoder := decodebencode.NewDecoder(strings.NewReader(input))
+
+	decoder.OnPath("creation date", func(path decodebencode.Path, value interface{}) (interface{}, error) {
+		return time.Unix(int64(value.(int)), 0).UTC(), nil
+	})
+
+	decoder.OnMatch(func(path decodebencode.Path, value interface{}) bool {
+		return len(path) > 0 && path[len(path)-1].Key == "peers"
+	}, func(path decodebencode.Path, value interface{}) (interface{}, error) {
+		compact := []byte(value.(string))
+		result := make([]netip.AddrPort, 0, len(compact)/6)
+		for i := 0; i+6 <= len(compact); i += 6 {
+			addr := netip.AddrFrom4([4]byte(compact[i : i+4]))
+			result = append(result, netip.AddrPortFrom(addr, uint16(compact[i+4])<<8|uint16(compact[i+5])))
+		}
+		return result, nil
+	})
+
+	var seen []string
+	decoder.OnMatch(func(path decodebencode.Path, value interface{}) bool {
+		return true
+	}, func(path decodebencode.Path, value interface{}) (interface{}, error) {
+		seen = append(seen, path.String())
+		return value, nil
+	})
+
+	output, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"creation date": time.Unix(1700000000, 0).UTC(),
+		"info": map[string]interface{}{
+			"files": []interface{}{map[string]interface{}{"length": 7}},
+		},
+		"peers": []netip.AddrPort{netip.MustParseAddrPort("127.0.0.1:6881"), netip.MustParseAddrPort("10.0.0.2:80")},
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("Expected %v, got %v", expected, output)
+	}
+
+	expectedSeen := []string{"creation date", "info.files[0].length", "info.files[0]", "info.files", "info", "peers", ""}
+	if !reflect.DeepEqual(seen, expectedSeen) {
+		t.Errorf("Expected hooks to visit %v, got %v", expectedSeen, seen)
+	}
+}
+
+func TestDecoderHookError(t *testing.T) {
+	hookErr := errors.New("not a date")
+
+	decoder := decodebencode.NewDecoder(strings.NewReader("d4:infod4:name3:fooee"))
+	decoder.OnPath("info.name", func(path decodebencode.Path, value interface{}) (interface{}, error) {
+		return nil, hookErr
+	})
+
+	output, err := decoder.Decode()
+	if !errors.Is(err, hookErr) {
+		t.Errorf("Expected error wrapping %v, got %v", hookErr, err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "info.name") {
+		t.Errorf("Expected error to mention path, got %v", err)
+	}
+	if output != nil {
+		t.Errorf("Expected nil result, got %v", output)
+	}
+}
@@ -49,24 +49,99 @@ func (s *DataStack) Pop() (interface{}, error) {
 	return result, nil
 }
 
+// How DecodeBencode resolves a key that appears more than once in a dictionary
+type DuplicateKeyPolicy int
+
+const (
+	// the last value replaces the earlier ones
+	DuplicateKeysLastWins DuplicateKeyPolicy = iota
+	// the first value is kept, later ones are ignored
+	DuplicateKeysFirstWins
+	// decoding fails with *DuplicateKeyError
+	DuplicateKeysReject
+	// all values are kept in input order as []interface{}
+	DuplicateKeysCollect
+)
+
+// repeated dictionary key, offsets point to the key in the input
+type DuplicateKey struct {
+	Key         string
+	FirstOffset int
+	Offset      int
+}
+
+type DuplicateKeyError struct {
+	DuplicateKey
+}
+
+func (e *DuplicateKeyError) Error() string {
+	return fmt.Sprintf("duplicate dict key %q on index %d, first seen on index %d", e.Key, e.Offset, e.FirstOffset)
+}
+
 // Transforms sequence of elements in buff to map (bencode dictionary)
 func ShrinkDictionary(stackSlice []interface{}) (map[string]interface{}, error) {
-	if len(stackSlice)%2 != 0 {
-		return nil, fmt.Errorf("cannot transform stackSlice to map because odd number of elements: %v (%d)", stackSlice, len(stackSlice))
-	}
 	slices.Reverse(stackSlice)
-	dict := make(map[string]interface{})
-	for i := 0; i < len(stackSlice); i += 2 {
-		key, ok_key := stackSlice[i].(string)
+	dict, _, err := shrinkDictionary(stackSlice, nil, DuplicateKeysLastWins)
+	return dict, err
+}
+
+// Same as ShrinkDictionary but takes elements in input order. offsets are
+// positions of the elements in the input, when nil indexes in items are used.
+func shrinkDictionary(items []interface{}, offsets []int, policy DuplicateKeyPolicy) (map[string]interface{}, []DuplicateKey, error) {
+	if len(items)%2 != 0 {
+		return nil, nil, fmt.Errorf("cannot transform stackSlice to map because odd number of elements: %v (%d)", items, len(items))
+	}
+
+	offset := func(i int) int {
+		if offsets == nil {
+			return i
+		}
+		return offsets[i]
+	}
+
+	dict := make(map[string]interface{}, len(items)/2)
+	var duplicates []DuplicateKey
+	var collected map[string]bool
+
+	for i := 0; i < len(items); i += 2 {
+		key, ok_key := items[i].(string)
 
 		if !ok_key {
-			return nil, fmt.Errorf("value %v cannot be used as dict key", stackSlice[i])
+			return nil, nil, fmt.Errorf("value %v cannot be used as dict key", items[i])
 		}
 
-		dict[key] = stackSlice[i+1]
+		current, exists := dict[key]
+		if !exists {
+			dict[key] = items[i+1]
+			continue
+		}
+
+		first := 0
+		for items[first] != key {
+			first += 2
+		}
+		duplicate := DuplicateKey{Key: key, FirstOffset: offset(first), Offset: offset(i)}
+		duplicates = append(duplicates, duplicate)
+
+		switch policy {
+		case DuplicateKeysReject:
+			return nil, duplicates, &DuplicateKeyError{duplicate}
+		case DuplicateKeysFirstWins:
+		case DuplicateKeysCollect:
+			if collected == nil {
+				collected = make(map[string]bool)
+			}
+			if !collected[key] {
+				current = []interface{}{current}
+				collected[key] = true
+			}
+			dict[key] = append(current.([]interface{}), items[i+1])
+		default:
+			dict[key] = items[i+1]
+		}
 	}
 
-	return dict, nil
+	return dict, duplicates, nil
 }
 
 func ShrinkStack(stack *DataStack) error {
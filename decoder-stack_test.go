@@ -129,6 +129,11 @@ func TestShrinkDictionary(t *testing.T) {
 			input: []interface{}{},
 			want:  map[string]interface{}{},
 		},
+		{
+			name:  "Duplicate key keeps last value",
+			input: []interface{}{"value2", "key", "value1", "key"},
+			want:  map[string]interface{}{"key": "value2"},
+		},
 		{
 			name:  "Mixed types in values",
 			input: []interface{}{42, "x", true, "y"},
@@ -1,12 +1,14 @@
 package decodebencode
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math"
 	"strconv"
 	"strings"
-	"unicode"
-	"unicode/utf8"
+	"sync"
 )
 
 // finds next [r] rune int input, useful for
@@ -37,103 +39,488 @@ func ParseInt(input []rune) (int, error) {
 	return num, nil
 }
 
+// how many tokens are parsed between two checks of ctx for cancellation
+const cancelCheckInterval = 256
+
+// size of the chunks Decoder reads from its source
+const readChunkSize = 32 * 1024
+
+// states with a stack grown bigger than that are not put back to the pool
+const maxPooledStackSize = 4096
+
+var decodeStatePool = sync.Pool{
+	New: func() any {
+		return new(decodeState)
+	},
+}
+
+func getDecodeState() *decodeState {
+	return decodeStatePool.Get().(*decodeState)
+}
+
+func putDecodeState(s *decodeState) {
+	if cap(s.stack) > maxPooledStackSize {
+		return
+	}
+	s.reset()
+	s.policy = DuplicateKeysLastWins
+	s.hooks = nil
+	s.intern = nil
+	decodeStatePool.Put(s)
+}
+
+// Decoder reads bencoded values from an io.Reader and decodes them to the same
+// interface{} tree DecodeBencode returns. Every Decode call reads until one
+// complete value is buffered, bytes read past it are kept for the next call, so
+// a connection that stays open after a message doesn't block Decode. Once the
+// reader ends with no value left Decode returns io.EOF. Buffers are kept
+// between Decode calls, so one Decoder pointed at new sources with Reset parses
+// without allocating anything but the decoded values.
+type Decoder struct {
+	r   io.Reader
+	buf []byte
+	// start of the bytes in buf read past the last decoded value
+	next int
+	// stream offset of buf[0], errors tell offsets from the start of the stream
+	offset     int
+	state      decodeState
+	policy     DuplicateKeyPolicy
+	duplicates []DuplicateKey
+	hooks      []decodeHook
+	intern     *InternTable
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Points decoder to r, options and buffers are kept
+func (d *Decoder) Reset(r io.Reader) {
+	d.r = r
+	d.buf = d.buf[:0]
+	d.next = 0
+	d.offset = 0
+	d.duplicates = nil
+}
+
+func (d *Decoder) Decode() (interface{}, error) {
+	return d.DecodeContext(context.Background())
+}
+
+// Same as Decode, but gives up as soon as ctx is done. The returned error
+// wraps ctx.Err() and tells at which stream offset decoding stopped, bytes
+// read so far are kept for the next call. ctx is checked between reads, a Read
+// that blocks is not interrupted, so connections need their own deadlines.
+func (d *Decoder) DecodeContext(ctx context.Context) (interface{}, error) {
+	rest := copy(d.buf, d.buf[d.next:])
+	d.offset += d.next
+	d.next = 0
+
+	data, end, err := readValueContext(ctx, d.r, d.buf[:rest], d.offset)
+	d.buf = data
+
+	if err != nil {
+		return nil, err
+	}
+	d.next = end
+	data = data[:end]
+
+	d.state.reset()
+	d.state.base = d.offset
+	d.state.policy = d.policy
+	d.state.hooks = d.hooks
+	d.state.intern = d.intern
+
+	output, err := decodeInput(ctx, &d.state, data)
+	d.duplicates = d.state.duplicates
+
+	return output, err
+}
+
+// Sets how repeated dict keys are resolved, DuplicateKeysLastWins by default
+func (d *Decoder) SetDuplicateKeyPolicy(policy DuplicateKeyPolicy) {
+	d.policy = policy
+}
+
+// Makes decoded dict keys and short strings share memory through table. The
+// same table can be given to several decoders as long as they don't run concurrently.
+func (d *Decoder) SetInternTable(table *InternTable) {
+	d.intern = table
+}
+
+// Repeated dict keys met by the last Decode call, in the order they were found
+func (d *Decoder) Duplicates() []DuplicateKey {
+	return d.duplicates
+}
+
+// Decodes the first value r has to offer, checking ctx between reads and while
+// parsing. Bytes read past the value are dropped, an empty r gives nil, as
+// DecodeBencode does for empty input.
+func DecodeContext(ctx context.Context, r io.Reader) (interface{}, error) {
+	output, err := NewDecoder(r).DecodeContext(ctx)
+	if err == io.EOF {
+		return nil, nil
+	}
+	return output, err
+}
+
+func canceledError(offset int, err error) error {
+	return fmt.Errorf("decoding canceled at offset %d: %w", offset, err)
+}
+
+// Reads from r, appending to data, until data holds one complete value or r
+// ends. Returns data and the offset where the value ends, or io.EOF when r
+// ended with nothing but blanks in data. ctx is checked before every read,
+// data is returned on errors as well. base is the stream offset of data.
+func readValueContext(ctx context.Context, r io.Reader, data []byte, base int) ([]byte, int, error) {
+	if cap(data) == 0 {
+		data = make([]byte, 0, 512)
+	}
+
+	scanner := valueScanner{}
+
+	for {
+		if end, ok := scanner.end(data); ok {
+			return data, end, nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return data, 0, canceledError(base+len(data), err)
+		}
+
+		if len(data) == cap(data) {
+			data = append(data, 0)[:len(data)]
+		}
+
+		n, err := r.Read(data[len(data):min(cap(data), len(data)+readChunkSize)])
+		data = data[:len(data)+n]
+
+		if err == io.EOF {
+			if isBlank(data) {
+				return data[:0], 0, io.EOF
+			}
+			return data, len(data), nil
+		}
+
+		if err != nil {
+			return data, 0, fmt.Errorf("cannot read bencode input at offset %d: %w", base+len(data), err)
+		}
+	}
+}
+
+// finds where the first value of a growing input ends, bytes already looked
+// at are not scanned again
+type valueScanner struct {
+	pos   int
+	depth int
+}
+
+// Offset right after the first complete value of data, or false when more
+// data is needed. Anything that is not bencode ends the value as well, so
+// decoding reports it without waiting for more input.
+func (s *valueScanner) end(data []byte) (int, bool) {
+	for s.pos < len(data) {
+		switch c := data[s.pos]; {
+		case c == INT_CONTROL_SYMBOL:
+			end := indexByte(data, s.pos, CLOSE_CONTROL_SYMBOL)
+			if end < 0 {
+				return 0, false
+			}
+			s.pos = end + 1
+
+		case c == LIST_CONTROL_SYMBOL || c == DICT_CONTROL_SYMBOL:
+			s.depth++
+			s.pos++
+			continue
+
+		case c == CLOSE_CONTROL_SYMBOL:
+			s.depth--
+			s.pos++
+
+		case c >= '0' && c <= '9':
+			colon := indexByte(data, s.pos, STR_CONTROL_SYMBOL)
+			if colon < 0 {
+				return 0, false
+			}
+			length, ok := parseInteger(data[s.pos:colon])
+			if !ok {
+				return len(data), true
+			}
+			if len(data)-colon-1 < length {
+				return 0, false
+			}
+			s.pos = colon + 1 + length
+
+		default:
+			return len(data), true
+		}
+
+		if s.depth <= 0 {
+			return s.pos, true
+		}
+	}
+
+	return 0, false
+}
+
+// Decoded strings are substrings of input, so they share its memory
 func DecodeBencode(input string) (interface{}, error) {
-	if len(strings.TrimSpace(input)) == 0 {
+	state := getDecodeState()
+	defer putDecodeState(state)
+
+	return decodeInput(context.Background(), state, input)
+}
+
+// everything a single decoding pass needs, the stack and the input offset of
+// every element on it
+type decodeState struct {
+	stack DataStack
+	// input offset of every element of stack
+	offsets []int
+	// stack indexes of list and dict markers that are not closed yet
+	containers []int
+	policy     DuplicateKeyPolicy
+	duplicates []DuplicateKey
+	hooks      []decodeHook
+	intern     *InternTable
+	// stream offset of the input, added to offsets of cancellation errors
+	base int
+}
+
+// prepares state for the next input, keeping allocated buffers
+func (s *decodeState) reset() {
+	clear(s.stack[:cap(s.stack)])
+	s.stack = s.stack[:0]
+	s.offsets = s.offsets[:0]
+	s.containers = s.containers[:0]
+	s.duplicates = nil
+	s.base = 0
+}
+
+func (s *decodeState) push(v interface{}, offset int) {
+	s.stack.Push(v)
+	s.offsets = append(s.offsets, offset)
+}
+
+// pushes LIST_MARKER or DICT_MARKER and opens a new container
+func (s *decodeState) pushMarker(marker interface{}, offset int) {
+	s.containers = append(s.containers, len(s.stack))
+	s.push(marker, offset)
+}
+
+// true when next pushed element is a key of the innermost dict
+func (s *decodeState) expectsKey() bool {
+	if len(s.containers) == 0 {
+		return false
+	}
+	m := s.containers[len(s.containers)-1]
+	return IsDictSymbol(s.stack[m]) && (len(s.stack)-m-1)%2 == 0
+}
+
+// Replaces innermost open list or dict and its elements on the stack with
+// the finished container
+func (s *decodeState) shrink(offset int) error {
+	if len(s.containers) == 0 {
+		return fmt.Errorf("unexpected closing symbol %v on index %d, there is no open list or dictionary", string(CLOSE_CONTROL_SYMBOL), offset)
+	}
+
+	m := s.containers[len(s.containers)-1]
+	s.containers = s.containers[:len(s.containers)-1]
+
+	items := s.stack[m+1:]
+	var container interface{}
+
+	if IsDictSymbol(s.stack[m]) {
+		dict, duplicates, err := shrinkDictionary(items, s.offsets[m+1:], s.policy)
+		// a rejected key is reported as well
+		s.duplicates = append(s.duplicates, duplicates...)
+
+		if err != nil {
+			return err
+		}
+		container = dict
+	} else {
+		list := make([]interface{}, len(items))
+		copy(list, items)
+		container = list
+	}
+
+	start := s.offsets[m]
+	clear(items)
+	s.stack = s.stack[:m]
+	s.offsets = s.offsets[:m]
+
+	return s.pushValue(container, start)
+}
+
+// bencode is decoded either from a string or straight from a byte slice
+type bencodeInput interface {
+	~string | ~[]byte
+}
+
+// strconv.Atoi for strings and byte slices that doesn't allocate
+func parseInteger[T bencodeInput](digits T) (int, bool) {
+	i := 0
+	negative := false
+
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		negative = digits[0] == '-'
+		i++
+	}
+
+	if i == len(digits) {
+		return 0, false
+	}
+
+	limit := uint64(math.MaxInt)
+	if negative {
+		limit++
+	}
+
+	var n uint64
+	for ; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, false
+		}
+
+		digit := uint64(digits[i] - '0')
+		if n > (limit-digit)/10 {
+			return 0, false
+		}
+		n = n*10 + digit
+	}
+
+	if negative {
+		return -int(n), true
+	}
+
+	return int(n), true
+}
+
+// strings.TrimSpace(input) == ""
+func isBlank[T bencodeInput](input T) bool {
+	for i := 0; i < len(input); i++ {
+		switch input[i] {
+		case ' ', '\t', '\n', '\v', '\f', '\r':
+		default:
+			return input[i] >= 0x80 && len(strings.TrimSpace(string(input))) == 0
+		}
+	}
+
+	return true
+}
+
+func indexByte[T bencodeInput](input T, start int, b byte) int {
+	for i := start; i < len(input); i++ {
+		if input[i] == b {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func decodeInput[T bencodeInput](ctx context.Context, s *decodeState, input T) (interface{}, error) {
+	if isBlank(input) {
 		return nil, nil
 	}
 
 	i := 0
-	r_input := []rune(input)
-	stack := make(DataStack, 0, 1)
+	steps := 0
 
-	for i < len(r_input) {
-		switch r_input[i] {
-		case INT_CONTROL_SYMBOL:
-			i++
-			start := i
+	for i < len(input) {
+		if steps%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, canceledError(s.base+i, err)
+			}
+		}
+		steps++
 
-			end, found_end := FindNextRune(start, CLOSE_CONTROL_SYMBOL, r_input)
+		switch input[i] {
+		case INT_CONTROL_SYMBOL:
+			start := i + 1
+			end := indexByte(input, start, CLOSE_CONTROL_SYMBOL)
 
-			if !found_end {
-				return nil, fmt.Errorf("cannot find closing symbol %v for integer, starting from: %d in %s", CLOSE_CONTROL_SYMBOL, start, string(r_input))
+			if end < 0 {
+				return nil, fmt.Errorf("cannot find closing symbol %v for integer, starting from: %d in %s", string(CLOSE_CONTROL_SYMBOL), start, string(input))
 			}
 
-			num, err_parse_int := ParseInt(r_input[start:end])
+			num, ok := parseInteger(input[start:end])
 
-			if err_parse_int != nil {
-				fmt.Println(err_parse_int)
-				return nil, err_parse_int
+			if !ok {
+				return nil, fmt.Errorf("cannot convert %q to int on index %d", string(input[start:end]), start)
 			}
 
-			stack.Push(num)
+			if err := s.pushValue(num, i); err != nil {
+				return nil, err
+			}
 			i = end + 1
 
 		case LIST_CONTROL_SYMBOL:
-			stack.Push(LIST_MARKER)
+			s.pushMarker(LIST_MARKER, i)
 			i++
 
 		case DICT_CONTROL_SYMBOL:
-			stack.Push(DICT_MARKER)
+			s.pushMarker(DICT_MARKER, i)
 			i++
 
 		case CLOSE_CONTROL_SYMBOL:
-			if i < len(r_input) {
-				zip_error := ShrinkStack(&stack)
-
-				if zip_error != nil {
-					fmt.Println(zip_error)
-					return nil, zip_error
-				}
+			if err := s.shrink(i); err != nil {
+				return nil, err
 			}
 			i++
-			// try to parse string
+
+		// try to parse string
 		default:
-			if !unicode.IsDigit(r_input[i]) {
-				return nil, fmt.Errorf("parsing error, expected digit, got %v on index %d", string(r_input[i-2:]), i)
+			if input[i] < '0' || input[i] > '9' {
+				return nil, fmt.Errorf("parsing error, expected digit, got %v on index %d", string(input[i:]), i)
 			}
 
 			start := i
-			semicolon_index, found_semicolon_index := FindNextRune(start, STR_CONTROL_SYMBOL, r_input)
+			semicolon_index := indexByte(input, start, STR_CONTROL_SYMBOL)
 
-			if !found_semicolon_index {
-				return nil, fmt.Errorf("cannot find closing symbol %v for string, starting from: %d in %s", STR_CONTROL_SYMBOL, start, string(r_input))
+			if semicolon_index < 0 {
+				return nil, fmt.Errorf("cannot find closing symbol %v for string, starting from: %d in %s", string(STR_CONTROL_SYMBOL), start, string(input))
 			}
 
-			str_bytes_length, str_bytes_length_error := ParseInt(r_input[start:semicolon_index])
-			if str_bytes_length_error != nil {
-				fmt.Println(string(r_input[start:semicolon_index]))
-				return nil, str_bytes_length_error
-			}
-
-			if len(string(r_input[semicolon_index+1:])) < str_bytes_length {
-				return nil, fmt.Errorf("wrong string encoding: length of string %d is greater than remainng length of %v", str_bytes_length, string(r_input[semicolon_index+1:]))
+			str_bytes_length, ok := parseInteger(input[start:semicolon_index])
+			if !ok {
+				return nil, fmt.Errorf("cannot convert string length %q to int on index %d", string(input[start:semicolon_index]), start)
 			}
 
 			str_start_index := semicolon_index + 1
-
-			j := 0
-			str_end_index := str_start_index
-			for j < str_bytes_length {
-				j += utf8.RuneLen(r_input[str_end_index])
-				str_end_index++
+			if len(input)-str_start_index < str_bytes_length {
+				return nil, fmt.Errorf("wrong string encoding: length of string %d is greater than remainng length of %v", str_bytes_length, string(input[str_start_index:]))
 			}
+			str_end_index := str_start_index + str_bytes_length
 
-			str := string(r_input[str_start_index:str_end_index])
+			var str string
+			if s.intern != nil {
+				str = internInput(s.intern, input[str_start_index:str_end_index], s.expectsKey())
+			} else {
+				str = string(input[str_start_index:str_end_index])
+			}
 
-			stack.Push(str)
+			if err := s.pushValue(str, start); err != nil {
+				return nil, err
+			}
 			i = str_end_index
 		}
 	}
 
-	if len(stack) > 1 {
-		return nil, fmt.Errorf("wrong input data, faced sequence of unwrapped elements: %v", stack)
+	if len(s.stack) > 1 {
+		return nil, fmt.Errorf("wrong input data, faced sequence of unwrapped elements: %v", s.stack)
 	}
 
-	el, err := stack.Pop()
+	el, err := s.stack.Pop()
 
 	if err != nil {
 		return nil, err
 	}
 
+	if IsDictSymbol(el) || IsListSymbol(el) {
+		return nil, fmt.Errorf("wrong input data, list or dictionary starting on index %d is not closed", s.offsets[0])
+	}
+
 	return el, nil
 }
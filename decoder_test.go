@@ -1,8 +1,13 @@
 package decodebencode_test
 
 import (
+	"context"
+	"errors"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	decodebencode "github.com/jabakot/decode-bencode"
 )
@@ -144,6 +149,12 @@ func TestDecodeBencode(t *testing.T) {
 		{name: "list with all nested data types", input: "li42e2:hid6:answeri42e5:hello5:world2:hi4:mark4:jojo12:ゴゴゴゴ12:wrong-answeri-42eeli42e2:hiee", expected: test_list_complex, expectErr: false},
 		{name: "dictionary with all nested data types", input: "d6:answeri42e5:hello5:world4:listli42e2:hie4:dictd6:answeri42e5:hello5:world2:hi4:mark4:jojo12:ゴゴゴゴ12:wrong-answeri-42eee", expected: test_map_complex, expectErr: false},
 		{name: "sequence of elements without dict or list wrap", input: "3:hi!i42e", expected: nil, expectErr: true},
+		{name: "list that is never closed", input: "l", expected: nil, expectErr: true},
+		{name: "closing symbol without list or dict", input: "i42ee", expected: nil, expectErr: true},
+		{name: "unknown symbol", input: "x", expected: nil, expectErr: true},
+		{name: "binary string", input: "4:\xff\x00\xfe\x01", expected: "\xff\x00\xfe\x01", expectErr: false},
+		{name: "integer overflow", input: "i9223372036854775808e", expected: nil, expectErr: true},
+		{name: "smallest integer", input: "i-9223372036854775808e", expected: -9223372036854775808, expectErr: false},
 	}
 
 	for _, tc := range testCases {
@@ -169,3 +180,293 @@ func TestDecodeBencode(t *testing.T) {
 	}
 
 }
+
+// cancels ctx once the wrapped reader has been read from
+type cancelingReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (c *cancelingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p[:min(len(p), 4)])
+	c.cancel()
+	return n, err
+}
+
+func TestDecodeContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	midRead, cancelMidRead := context.WithCancel(context.Background())
+	defer cancelMidRead()
+
+	type TestCase struct {
+		name      string
+		ctx       context.Context
+		input     io.Reader
+		expected  interface{}
+		expectErr error
+	}
+
+	testCases := []TestCase{
+		{name: "decodes reader", ctx: context.Background(), input: strings.NewReader("li42e2:hie"), expected: []interface{}{42, "hi"}},
+		{name: "empty reader", ctx: context.Background(), input: strings.NewReader(""), expected: nil},
+		{name: "already canceled", ctx: canceled, input: strings.NewReader("i42e"), expectErr: context.Canceled},
+		{name: "canceled while reading", ctx: midRead, input: &cancelingReader{r: strings.NewReader("li42e2:hie"), cancel: cancelMidRead}, expectErr: context.Canceled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := decodebencode.DecodeContext(tc.ctx, tc.input)
+			if tc.expectErr != nil {
+				if !errors.Is(err, tc.expectErr) {
+					t.Errorf("Expected error wrapping %v, got %v", tc.expectErr, err)
+				}
+				if err != nil && !strings.Contains(err.Error(), "offset") {
+					t.Errorf("Expected error to mention offset, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestDecoderDecodeContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	input := "l" + strings.Repeat("i1e", 10000) + "e"
+	_, err := decodebencode.NewDecoder(strings.NewReader(input)).DecodeContext(ctx)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+
+	output, err := decodebencode.NewDecoder(strings.NewReader(input)).Decode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if list, ok := output.([]interface{}); !ok || len(list) != 10000 {
+		t.Errorf("Expected list of 10000 elements, got %T", output)
+	}
+}
+
+func TestDecoderStopsAfterValue(t *testing.T) {
+	// the writing end stays open, as a connection waiting for a reply would
+	r, w := io.Pipe()
+	defer w.Close()
+
+	go w.Write([]byte("d1:q4:ping1:t2:aae"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		output, err := decodebencode.NewDecoder(r).Decode()
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		expected := map[string]interface{}{"q": "ping", "t": "aa"}
+		if !reflect.DeepEqual(output, expected) {
+			t.Errorf("Expected %v, got %v", expected, output)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Decode kept reading after a complete value")
+	}
+}
+
+func TestDecoderConsecutiveValues(t *testing.T) {
+	decoder := decodebencode.NewDecoder(&cancelingReader{r: strings.NewReader("i1e4:spamli2ee"), cancel: func() {}})
+
+	expected := []interface{}{1, "spam", []interface{}{2}}
+	for _, value := range expected {
+		output, err := decoder.Decode()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(output, value) {
+			t.Errorf("Expected %v, got %v", value, output)
+		}
+	}
+
+	// a loop reading until an error stops once the stream is over
+	for range 2 {
+		if output, err := decoder.Decode(); !errors.Is(err, io.EOF) {
+			t.Errorf("Expected io.EOF, got %v, %v", output, err)
+		}
+	}
+}
+
+func TestDecoderCanceledStreamOffset(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// reads 4 bytes at a time: `i1el` first, so the list is left unfinished
+	decoder := decodebencode.NewDecoder(&cancelingReader{r: strings.NewReader("i1eli42e2:hie"), cancel: cancel})
+
+	if output, err := decoder.Decode(); err != nil || output != 1 {
+		t.Fatalf("Expected 1, got %v, %v", output, err)
+	}
+
+	_, err := decoder.DecodeContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "offset 4:") {
+		t.Errorf("Expected offset from the start of the stream, got %v", err)
+	}
+
+	// bytes read before canceling are not lost
+	output, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []interface{}{42, "hi"}) {
+		t.Errorf("Expected %v, got %v", []interface{}{42, "hi"}, output)
+	}
+}
+
+func TestDecoderDuplicateKeys(t *testing.T) {
+	// key `a` is repeated on index 7 and 19, first seen on index 1
+	input := "d1:ai1e1:ai2e1:bi3e1:ai4ee"
+
+	type TestCase struct {
+		name      string
+		policy    decodebencode.DuplicateKeyPolicy
+		expected  interface{}
+		expectErr bool
+	}
+
+	testCases := []TestCase{
+		{name: "last wins by default", policy: decodebencode.DuplicateKeysLastWins, expected: map[string]interface{}{"a": 4, "b": 3}},
+		{name: "first wins", policy: decodebencode.DuplicateKeysFirstWins, expected: map[string]interface{}{"a": 1, "b": 3}},
+		{name: "collect", policy: decodebencode.DuplicateKeysCollect, expected: map[string]interface{}{"a": []interface{}{1, 2, 4}, "b": 3}},
+		{name: "reject", policy: decodebencode.DuplicateKeysReject, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoder := decodebencode.NewDecoder(strings.NewReader(input))
+			decoder.SetDuplicateKeyPolicy(tc.policy)
+
+			output, err := decoder.Decode()
+			if tc.expectErr {
+				var duplicateErr *decodebencode.DuplicateKeyError
+				if !errors.As(err, &duplicateErr) {
+					t.Fatalf("Expected *DuplicateKeyError, got %v", err)
+				}
+				expected := decodebencode.DuplicateKey{Key: "a", FirstOffset: 1, Offset: 7}
+				if duplicateErr.DuplicateKey != expected {
+					t.Errorf("Expected %v, got %v", expected, duplicateErr.DuplicateKey)
+				}
+				if !reflect.DeepEqual(decoder.Duplicates(), []decodebencode.DuplicateKey{expected}) {
+					t.Errorf("Expected duplicates %v, got %v", []decodebencode.DuplicateKey{expected}, decoder.Duplicates())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(output, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, output)
+			}
+
+			expectedDuplicates := []decodebencode.DuplicateKey{
+				{Key: "a", FirstOffset: 1, Offset: 7},
+				{Key: "a", FirstOffset: 1, Offset: 19},
+			}
+			if !reflect.DeepEqual(decoder.Duplicates(), expectedDuplicates) {
+				t.Errorf("Expected duplicates %v, got %v", expectedDuplicates, decoder.Duplicates())
+			}
+		})
+	}
+}
+
+func TestDecoderReset(t *testing.T) {
+	decoder := decodebencode.NewDecoder(strings.NewReader("d1:ai1e1:ai2ee"))
+	decoder.SetDuplicateKeyPolicy(decodebencode.DuplicateKeysFirstWins)
+
+	first, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoder.Reset(strings.NewReader("d1:bli1e1:xe1:bi3ee"))
+
+	second, err := decoder.Decode()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(first, map[string]interface{}{"a": 1}) {
+		t.Errorf("Expected first result to survive Reset, got %v", first)
+	}
+	if !reflect.DeepEqual(second, map[string]interface{}{"b": []interface{}{1, "x"}}) {
+		t.Errorf("Expected policy to survive Reset, got %v", second)
+	}
+	if len(decoder.Duplicates()) != 1 || decoder.Duplicates()[0].Key != "b" {
+		t.Errorf("Expected only duplicates of second input, got %v", decoder.Duplicates())
+	}
+}
+
+func BenchmarkDecodeBencode(b *testing.B) {
+	inputs := []struct {
+		name  string
+		input string
+	}{
+		{name: "integer", input: "i42e"},
+		{name: "short string", input: "4:spam"},
+		{name: "krpc ping", input: "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe"},
+	}
+
+	for _, in := range inputs {
+		b.Run(in.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := decodebencode.DecodeBencode(in.input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// steady state of a long living decoder: buffers are reused, only decoded values allocate
+func BenchmarkDecoderReuse(b *testing.B) {
+	inputs := []struct {
+		name  string
+		input string
+	}{
+		{name: "integer", input: "i42e"},
+		{name: "list of small integers", input: "li1ei2ei3ee"},
+		{name: "krpc ping", input: "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe"},
+	}
+
+	for _, in := range inputs {
+		b.Run(in.name, func(b *testing.B) {
+			reader := strings.NewReader(in.input)
+			decoder := decodebencode.NewDecoder(reader)
+			decoder.SetInternTable(decodebencode.NewInternTable(32, 64))
+
+			b.ReportAllocs()
+			for b.Loop() {
+				reader.Reset(in.input)
+				decoder.Reset(reader)
+				if _, err := decoder.Decode(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package decodebencode
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type ChangeKind int
+
+const (
+	ChangeAdded ChangeKind = iota + 1
+	ChangeRemoved
+	ChangeModified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	case ChangeModified:
+		return "modified"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Binary strings longer than this are shortened when rendered
+const maxRenderedBytes = 32
+
+// One difference found by Diff. Old is nil for added values, New is nil for
+// removed ones.
+type Change struct {
+	Kind ChangeKind
+	Path Path
+	Old  interface{}
+	New  interface{}
+}
+
+// Renders the change on one line, e.g. `~ info.name: "a.iso" -> "b.iso"`.
+// Strings that are not printable UTF-8 are shown as hex, the root path as
+// `(root)`.
+func (c Change) String() string {
+	path := c.Path.String()
+	if len(c.Path) == 0 {
+		path = "(root)"
+	}
+
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", path, RenderValue(c.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", path, RenderValue(c.Old))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", path, RenderValue(c.Old), RenderValue(c.New))
+}
+
+// Compares two trees DecodeBencode returned. Dict keys that only b has are
+// added, keys that only a has are removed, list elements are compared by
+// index, so a list that grows or shrinks gets its elements at the end added
+// or removed. Values of different types or with different contents are
+// modified, values other than dicts, lists, integers and strings are compared
+// with reflect.DeepEqual. Changes are ordered by path, with dict keys in sorted order.
+func Diff(a, b interface{}) []Change {
+	var changes []Change
+	diffValues(&changes, Path{}, a, b)
+	return changes
+}
+
+// Same as Diff, but decodes both documents first
+func DiffBencode(a, b []byte) ([]Change, error) {
+	treeA, err := DecodeBencode(string(a))
+	if err != nil {
+		return nil, err
+	}
+	treeB, err := DecodeBencode(string(b))
+	if err != nil {
+		return nil, err
+	}
+	return Diff(treeA, treeB), nil
+}
+
+// Renders changes one per line, as Change.String does
+func FormatDiff(changes []Change) string {
+	var sb strings.Builder
+	for _, change := range changes {
+		sb.WriteString(change.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func diffValues(changes *[]Change, path Path, a, b interface{}) {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		if b, ok := b.(map[string]interface{}); ok {
+			diffDicts(changes, path, a, b)
+			return
+		}
+	case []interface{}:
+		if b, ok := b.([]interface{}); ok {
+			diffLists(changes, path, a, b)
+			return
+		}
+	case int:
+		if b, ok := b.(int); ok && a == b {
+			return
+		}
+	case string:
+		if b, ok := b.(string); ok && a == b {
+			return
+		}
+	default:
+		// nil and values decode hooks put in the tree
+		if reflect.DeepEqual(a, b) {
+			return
+		}
+	}
+
+	*changes = append(*changes, Change{Kind: ChangeModified, Path: path, Old: a, New: b})
+}
+
+func diffDicts(changes *[]Change, path Path, a, b map[string]interface{}) {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		keyPath := childPath(path, PathSegment{Key: key})
+		valueA, inA := a[key]
+		valueB, inB := b[key]
+
+		switch {
+		case !inA:
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: keyPath, New: valueB})
+		case !inB:
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: keyPath, Old: valueA})
+		default:
+			diffValues(changes, keyPath, valueA, valueB)
+		}
+	}
+}
+
+func diffLists(changes *[]Change, path Path, a, b []interface{}) {
+	for i := range max(len(a), len(b)) {
+		indexPath := childPath(path, PathSegment{Index: i, IsIndex: true})
+
+		switch {
+		case i >= len(a):
+			*changes = append(*changes, Change{Kind: ChangeAdded, Path: indexPath, New: b[i]})
+		case i >= len(b):
+			*changes = append(*changes, Change{Kind: ChangeRemoved, Path: indexPath, Old: a[i]})
+		default:
+			diffValues(changes, indexPath, a[i], b[i])
+		}
+	}
+}
+
+// Renders a tree DecodeBencode returned on one line: integers as they are,
+// printable strings quoted, other strings as hex, e.g. `hex:00ff` or
+// `hex:0102…(40 bytes)` when they are long. Lists render as `[1, "a"]`, dicts
+// as `{"k": 1}` with sorted keys.
+func RenderValue(tree interface{}) string {
+	var sb strings.Builder
+	renderValue(&sb, tree)
+	return sb.String()
+}
+
+func renderValue(sb *strings.Builder, tree interface{}) {
+	switch t := tree.(type) {
+	case int:
+		sb.WriteString(strconv.Itoa(t))
+	case string:
+		renderString(sb, t)
+	case []interface{}:
+		sb.WriteByte('[')
+		for i, element := range t {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			renderValue(sb, element)
+		}
+		sb.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for key := range t {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+
+		sb.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			renderString(sb, key)
+			sb.WriteString(": ")
+			renderValue(sb, t[key])
+		}
+		sb.WriteByte('}')
+	case nil:
+		sb.WriteString("nil")
+	default:
+		fmt.Fprintf(sb, "%v", t)
+	}
+}
+
+func renderString(sb *strings.Builder, s string) {
+	if isPrintable(s) {
+		sb.WriteString(strconv.Quote(s))
+		return
+	}
+
+	sb.WriteString("hex:")
+	if len(s) <= maxRenderedBytes {
+		sb.WriteString(hex.EncodeToString([]byte(s)))
+		return
+	}
+	sb.WriteString(hex.EncodeToString([]byte(s[:maxRenderedBytes])))
+	fmt.Fprintf(sb, "…(%d bytes)", len(s))
+}
@@ -0,0 +1,178 @@
+package decodebencode_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func TestDiff(t *testing.T) {
+	type TestCase struct {
+		name     string
+		a        string
+		b        string
+		expected []decodebencode.Change
+	}
+
+	testCases := []TestCase{
+		{name: "equal", a: "d1:ai1e1:bl1:xee", b: "d1:ai1e1:bl1:xee", expected: nil},
+		{
+			name: "added and removed keys",
+			a:    "d1:ai1e1:bi2ee",
+			b:    "d1:bi2e1:ci3ee",
+			expected: []decodebencode.Change{
+				{Kind: decodebencode.ChangeRemoved, Path: decodebencode.Path{{Key: "a"}}, Old: 1},
+				{Kind: decodebencode.ChangeAdded, Path: decodebencode.Path{{Key: "c"}}, New: 3},
+			},
+		},
+		{
+			name: "modified nested value",
+			a:    "d4:infod5:filesld4:pathl1:aeeeee",
+			b:    "d4:infod5:filesld4:pathl1:beeeee",
+			expected: []decodebencode.Change{
+				{
+					Kind: decodebencode.ChangeModified,
+					Path: decodebencode.Path{{Key: "info"}, {Key: "files"}, {Index: 0, IsIndex: true}, {Key: "path"}, {Index: 0, IsIndex: true}},
+					Old:  "a",
+					New:  "b",
+				},
+			},
+		},
+		{
+			name: "list grows and shrinks",
+			a:    "l1:a1:bl1:c1:dee",
+			b:    "l1:a1:xl1:cei1ee",
+			expected: []decodebencode.Change{
+				{Kind: decodebencode.ChangeModified, Path: decodebencode.Path{{Index: 1, IsIndex: true}}, Old: "b", New: "x"},
+				{Kind: decodebencode.ChangeRemoved, Path: decodebencode.Path{{Index: 2, IsIndex: true}, {Index: 1, IsIndex: true}}, Old: "d"},
+				{Kind: decodebencode.ChangeAdded, Path: decodebencode.Path{{Index: 3, IsIndex: true}}, New: 1},
+			},
+		},
+		{
+			name: "type changed",
+			a:    "d1:ai1ee",
+			b:    "d1:a1:1e",
+			expected: []decodebencode.Change{
+				{Kind: decodebencode.ChangeModified, Path: decodebencode.Path{{Key: "a"}}, Old: 1, New: "1"},
+			},
+		},
+		{
+			name: "root modified",
+			a:    "i1e",
+			b:    "i2e",
+			expected: []decodebencode.Change{
+				{Kind: decodebencode.ChangeModified, Path: decodebencode.Path{}, Old: 1, New: 2},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		result, err := decodebencode.DiffBencode([]byte(tc.a), []byte(tc.b))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%s: Expected %v, got %v", tc.name, tc.expected, result)
+		}
+	}
+
+	// values decode hooks may leave in a tree
+	if changes := decodebencode.Diff(map[string]interface{}{"a": []byte{1, 2}, "b": nil}, map[string]interface{}{"a": []byte{1, 2}, "b": nil}); changes != nil {
+		t.Errorf("Expected no changes for equal values, got %v", changes)
+	}
+
+	if changes, err := decodebencode.DiffBencode([]byte(""), []byte("")); err != nil || changes != nil {
+		t.Errorf("Expected no changes for empty inputs, got %v, %v", changes, err)
+	}
+
+	if _, err := decodebencode.DiffBencode([]byte("i1e"), []byte("i1")); err == nil {
+		t.Errorf("Expected an error for invalid input")
+	}
+}
+
+func TestFormatDiff(t *testing.T) {
+	a := "d8:announce7:udp://a4:infod4:name5:a.iso6:pieces4:\x00\xff\x00\x01ee"
+	b := "d4:infod4:name5:b.iso6:pieces4:\x00\xff\x00\x027:privatei1eee"
+
+	changes, err := decodebencode.DiffBencode([]byte(a), []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := strings.Join([]string{
+		`- announce: "udp://a"`,
+		`~ info.name: "a.iso" -> "b.iso"`,
+		`~ info.pieces: hex:00ff0001 -> hex:00ff0002`,
+		`+ info.private: 1`,
+		``,
+	}, "\n")
+
+	result := decodebencode.FormatDiff(changes)
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestFormatDiffRoot(t *testing.T) {
+	changes, err := decodebencode.DiffBencode([]byte("i1e"), []byte("2:ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `~ (root): 1 -> "ab"` + "\n"
+	result := decodebencode.FormatDiff(changes)
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestFormatDiffBinaryKey(t *testing.T) {
+	// scrape responses key files by raw infohashes
+	changes, err := decodebencode.DiffBencode([]byte("d5:filesd4:\x00\xff\x1b\x07i1eee"), []byte("d5:filesd4:\x00\xff\x1b\x07i2eee"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `~ files["\x00\xff\x1b\a"]: 1 -> 2` + "\n"
+	result := decodebencode.FormatDiff(changes)
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestRenderValue(t *testing.T) {
+	type TestCase struct {
+		input    interface{}
+		expected string
+	}
+
+	testCases := []TestCase{
+		{input: -5, expected: "-5"},
+		{input: "a \"b\"", expected: `"a \"b\""`},
+		{input: "\x00\x01", expected: "hex:0001"},
+		{input: "a\nb", expected: "hex:610a62"},
+		{input: strings.Repeat("\xff", 40), expected: "hex:" + strings.Repeat("ff", 32) + "…(40 bytes)"},
+		{input: []interface{}{1, "a", []interface{}{}}, expected: `[1, "a", []]`},
+		{input: map[string]interface{}{"b": 1, "a": map[string]interface{}{}, "\xff": "x"}, expected: `{"a": {}, "b": 1, hex:ff: "x"}`},
+		{input: nil, expected: "nil"},
+	}
+
+	for _, tc := range testCases {
+		result := decodebencode.RenderValue(tc.input)
+		if result != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, result)
+		}
+	}
+}
+
+func TestChangeKindString(t *testing.T) {
+	if decodebencode.ChangeModified.String() != "modified" {
+		t.Errorf("Expected %q, got %q", "modified", decodebencode.ChangeModified.String())
+	}
+	if decodebencode.ChangeKind(0).String() != "ChangeKind(0)" {
+		t.Errorf("Expected %q, got %q", "ChangeKind(0)", decodebencode.ChangeKind(0).String())
+	}
+}
@@ -0,0 +1,61 @@
+package decodebencode
+
+import (
+	"hash"
+	"io"
+	"reflect"
+)
+
+// Encoder writes bencode of values straight to an io.Writer. Output is
+// buffered internally and written in chunks, so big values never have to fit
+// in memory as a whole.
+type Encoder struct {
+	state encodeState
+}
+
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{state: encodeState{w: w}}
+}
+
+// Writes bencode of v, accepting everything Marshal does. Everything is
+// flushed to the writer before Encode returns. When v cannot be encoded
+// nothing more is written, but chunks already flushed stay in the writer. Once
+// the writer fails, every following call returns the same error.
+func (enc *Encoder) Encode(v any) error {
+	e := &enc.state
+	if e.err != nil {
+		return e.err
+	}
+
+	if err := e.marshal(reflect.ValueOf(v)); err != nil {
+		e.buf = e.buf[:0]
+		if e.err != nil {
+			return e.err
+		}
+		return err
+	}
+
+	return e.flush()
+}
+
+// Writes bencode of v to w in chunks of a few KiB, the whole output is never
+// held in memory. Same as NewEncoder(w).Encode(v).
+func EncodeTo(w io.Writer, v any) error {
+	return NewEncoder(w).Encode(v)
+}
+
+// Digest of the bencode of v, fed to the hash while it is encoded:
+//
+//	infohash, err := decodebencode.HashOf(info, sha1.New)
+//
+// Keys are sorted as Marshal sorts them, so unless some part of v implements
+// Marshaler this is the hash of the canonical encoding.
+func HashOf(v any, newHash func() hash.Hash) ([]byte, error) {
+	h := newHash()
+
+	if err := EncodeTo(h, v); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
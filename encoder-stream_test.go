@@ -0,0 +1,165 @@
+package decodebencode_test
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+// records size of every write and fails once limit bytes were written
+type limitedWriter struct {
+	writes  []int
+	written int
+	limit   int
+}
+
+var errWriterFull = errors.New("writer is full")
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	if w.written+len(p) > w.limit {
+		n := w.limit - w.written
+		w.written = w.limit
+		return n, errWriterFull
+	}
+	w.written += len(p)
+	return len(p), nil
+}
+
+func TestEncoderEncode(t *testing.T) {
+	var out bytes.Buffer
+	encoder := decodebencode.NewEncoder(&out)
+
+	values := []any{
+		42,
+		"hi!",
+		[]any{1, "x"},
+		map[string]any{"b": []int{1}, "a": "ゴ"},
+	}
+
+	for _, v := range values {
+		if err := encoder.Encode(v); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := "i42e3:hi!li1e1:xed1:a3:ゴ1:bli1eee"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestEncoderEncodeInChunks(t *testing.T) {
+	list := make([]string, 1000)
+	for i := range list {
+		list[i] = strings.Repeat("x", 20)
+	}
+
+	w := &limitedWriter{limit: 1 << 20}
+	if err := decodebencode.NewEncoder(w).Encode(list); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected, _ := decodebencode.Marshal(list)
+	if w.written != len(expected) {
+		t.Errorf("Expected %d bytes written, got %d", len(expected), w.written)
+	}
+	if len(w.writes) < 2 {
+		t.Errorf("Expected output to be written in several chunks, got %v", w.writes)
+	}
+	for _, n := range w.writes {
+		if n > 2*4096 {
+			t.Errorf("Expected writes of a few KiB at most, got one of %d bytes", n)
+		}
+	}
+}
+
+func TestEncoderWriteError(t *testing.T) {
+	w := &limitedWriter{limit: 10}
+	encoder := decodebencode.NewEncoder(w)
+
+	err := encoder.Encode(strings.Repeat("x", 10000))
+	if !errors.Is(err, errWriterFull) {
+		t.Fatalf("Expected %v, got %v", errWriterFull, err)
+	}
+
+	err = encoder.Encode(1)
+	if !errors.Is(err, errWriterFull) {
+		t.Errorf("Expected error to stick, got %v", err)
+	}
+}
+
+func TestEncoderUnsupportedValue(t *testing.T) {
+	var out bytes.Buffer
+	encoder := decodebencode.NewEncoder(&out)
+
+	err := encoder.Encode([]any{1, 1.5})
+	var typeErr *decodebencode.UnsupportedTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected *UnsupportedTypeError, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing written for small value, got %q", out.String())
+	}
+
+	if err := encoder.Encode(1); err != nil {
+		t.Fatalf("Expected encoder to stay usable, got %v", err)
+	}
+	if out.String() != "i1e" {
+		t.Errorf("Expected %q, got %q", "i1e", out.String())
+	}
+}
+
+func TestEncodeTo(t *testing.T) {
+	var out bytes.Buffer
+	input := map[string]any{"name": "file", "length": 3}
+
+	if err := decodebencode.EncodeTo(&out, input); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "d6:lengthi3e4:name4:filee"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+
+	w := &limitedWriter{limit: 5}
+	if err := decodebencode.EncodeTo(w, input); !errors.Is(err, errWriterFull) {
+		t.Errorf("Expected errWriterFull, got %v", err)
+	}
+}
+
+func TestHashOf(t *testing.T) {
+	info := map[string]any{
+		"name":         "big.iso",
+		"piece length": 1 << 18,
+		"pieces":       strings.Repeat("\x00\xff", 10*4096),
+	}
+
+	expected, err := decodebencode.Marshal(info)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sha1Sum := sha1.Sum(expected)
+	sha256Sum := sha256.Sum256(expected)
+
+	output, err := decodebencode.HashOf(info, sha1.New)
+	if err != nil || !bytes.Equal(output, sha1Sum[:]) {
+		t.Errorf("Expected sha1 %x, got %x, %v", sha1Sum, output, err)
+	}
+
+	output, err = decodebencode.HashOf(info, sha256.New)
+	if err != nil || !bytes.Equal(output, sha256Sum[:]) {
+		t.Errorf("Expected sha256 %x, got %x, %v", sha256Sum, output, err)
+	}
+
+	if _, err := decodebencode.HashOf(map[string]any{"bad": 1.5}, sha1.New); err == nil {
+		t.Errorf("Expected an error for a float")
+	}
+}
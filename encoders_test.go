@@ -1,6 +1,7 @@
 package decodebencode_test
 
 import (
+	"errors"
 	"testing"
 
 	decodebencode "github.com/jabakot/decode-bencode"
@@ -11,10 +12,14 @@ type EncoderTestCase[I any] struct {
 	expected string
 }
 
-func tableRunner[I any](testTable []EncoderTestCase[I], encoder func(input I) string, t *testing.T) {
+func tableRunner[I any](testTable []EncoderTestCase[I], encoder func(input I) (string, error), t *testing.T) {
 	for _, test := range testTable {
 
-		output := encoder(test.input)
+		output, err := encoder(test.input)
+
+		if err != nil {
+			t.Errorf("got unexpected error %v for %v\n", err, test.input)
+		}
 
 		if output != test.expected {
 			t.Errorf("got %q, wanted %q\n", output, test.expected)
@@ -22,6 +27,13 @@ func tableRunner[I any](testTable []EncoderTestCase[I], encoder func(input I) st
 	}
 }
 
+// adapts encoders that cannot fail to tableRunner
+func infallible[I any](encoder func(input I) string) func(input I) (string, error) {
+	return func(input I) (string, error) {
+		return encoder(input), nil
+	}
+}
+
 func TestEncodeInteger(t *testing.T) {
 
 	testTable := []EncoderTestCase[int]{
@@ -33,7 +45,7 @@ func TestEncodeInteger(t *testing.T) {
 		{input: 0x00001, expected: "i1e"},
 	}
 
-	tableRunner(testTable, decodebencode.EncodeBencodeInteger, t)
+	tableRunner(testTable, infallible(decodebencode.EncodeBencodeInteger), t)
 
 }
 func TestEncodeString(t *testing.T) {
@@ -44,7 +56,7 @@ func TestEncodeString(t *testing.T) {
 		{input: "42", expected: "2:42"},
 	}
 
-	tableRunner(testTable, decodebencode.EncodeBencodeString, t)
+	tableRunner(testTable, infallible(decodebencode.EncodeBencodeString), t)
 }
 
 func TestEncodeList(t *testing.T) {
@@ -85,6 +97,8 @@ func TestEncodeList(t *testing.T) {
 
 	testTable := []EncoderTestCase[[]any]{
 		{input: make([]any, 0), expected: "le"},
+		{input: []any{42}, expected: "li42ee"},
+		{input: []any{""}, expected: "l0:e"},
 		{input: listWithIntAndString, expected: "li1e3:hi!e"},
 		{input: listWithLists, expected: "lli1e3:hi!eli1e3:hi!ee"},
 		{input: listWithDicts, expected: "ld5:hello5:world2:hi4:marked4:jojo12:ゴゴゴゴee"},
@@ -143,3 +157,63 @@ func TestEncodeDict(t *testing.T) {
 
 	tableRunner(testTable, decodebencode.EncodeBencodeDict, t)
 }
+
+func TestEncodeUnsupportedElements(t *testing.T) {
+	type TestCase struct {
+		name     string
+		encode   func() (string, error)
+		expected string
+	}
+
+	testCases := []TestCase{
+		{
+			name:     "list with channel",
+			encode:   func() (string, error) { return decodebencode.EncodeBencodeList([]any{1, make(chan int)}) },
+			expected: "bencode: unsupported type chan int at `[1]`",
+		},
+		{
+			name: "list with nested float",
+			encode: func() (string, error) {
+				return decodebencode.EncodeBencodeList([]any{[]any{map[string]any{"price": 9.99}}})
+			},
+			expected: "bencode: unsupported type float64 at `[0][0].price`",
+		},
+		{
+			name:     "dict with nil value",
+			encode:   func() (string, error) { return decodebencode.EncodeBencodeDict(map[string]any{"a": 1, "b": nil}) },
+			expected: "bencode: unsupported value nil interface {} at `b`",
+		},
+		{
+			name: "dict with bool in nested list",
+			encode: func() (string, error) {
+				return decodebencode.EncodeBencodeDict(map[string]any{"flags": []any{1, true}})
+			},
+			expected: "bencode: unsupported type bool at `flags[1]`",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := tc.encode()
+			if err == nil {
+				t.Fatalf("Expected error, got nil, result: %q", output)
+			}
+			if output != "" {
+				t.Errorf("Expected empty result, got %q", output)
+			}
+			if err.Error() != tc.expected {
+				t.Errorf("Expected error %q, got %q", tc.expected, err)
+			}
+		})
+	}
+
+	_, err := decodebencode.EncodeBencodeList([]any{"ok", []any{make(chan int)}})
+
+	var typeErr *decodebencode.UnsupportedTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected *UnsupportedTypeError, got %v", err)
+	}
+	if typeErr.Path.String() != "[1][0]" {
+		t.Errorf("Expected path [1][0], got %v", typeErr.Path)
+	}
+}
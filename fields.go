@@ -0,0 +1,182 @@
+package decodebencode
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// struct field as it appears in a bencode dictionary
+type field struct {
+	name string
+	// path from the outer struct through embedded and inlined structs
+	index     []int
+	depth     int
+	tagged    bool
+	omitEmpty bool
+	required  bool
+}
+
+// options following the name in a `bencode` struct tag
+type tagOptions string
+
+func (o tagOptions) has(option string) bool {
+	for current := range strings.SplitSeq(string(o), ",") {
+		if current == option {
+			return true
+		}
+	}
+	return false
+}
+
+func parseTag(tag string) (string, tagOptions) {
+	name, options, _ := strings.Cut(tag, ",")
+	return name, tagOptions(options)
+}
+
+// reflect.Type -> []field, sorted by name
+var fieldCache sync.Map
+
+func cachedFields(t reflect.Type) []field {
+	if fields, ok := fieldCache.Load(t); ok {
+		return fields.([]field)
+	}
+
+	var all []field
+	collectFields(t, nil, 0, map[reflect.Type]bool{t: true}, &all)
+
+	slices.SortStableFunc(all, func(a, b field) int {
+		return cmp.Compare(a.name, b.name)
+	})
+
+	fields := make([]field, 0, len(all))
+	for i := 0; i < len(all); {
+		j := i + 1
+		for j < len(all) && all[j].name == all[i].name {
+			j++
+		}
+		if dominant, ok := dominantField(all[i:j]); ok {
+			fields = append(fields, dominant)
+		}
+		i = j
+	}
+
+	actual, _ := fieldCache.LoadOrStore(t, fields)
+	return actual.([]field)
+}
+
+// appends fields of struct t, flattening embedded and inlined structs; seen
+// holds struct types on the current path, so recursive embedding terminates
+func collectFields(t reflect.Type, index []int, depth int, seen map[reflect.Type]bool, fields *[]field) {
+	for i := range t.NumField() {
+		sf := t.Field(i)
+
+		tag := sf.Tag.Get("bencode")
+		if tag == "-" {
+			continue
+		}
+		name, options := parseTag(tag)
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		flatten := ft.Kind() == reflect.Struct && ((sf.Anonymous && name == "") || options.has("inline"))
+		if !sf.IsExported() && !(sf.Anonymous && flatten) {
+			continue
+		}
+
+		fieldIndex := append(slices.Clone(index), i)
+
+		if flatten {
+			if !seen[ft] {
+				seen[ft] = true
+				collectFields(ft, fieldIndex, depth+1, seen, fields)
+				delete(seen, ft)
+			}
+			continue
+		}
+
+		*fields = append(*fields, field{
+			name:      cmp.Or(name, sf.Name),
+			index:     fieldIndex,
+			depth:     depth,
+			tagged:    name != "",
+			omitEmpty: options.has("omitempty"),
+			required:  options.has("required"),
+		})
+	}
+}
+
+// picks the field that wins among fields with the same name
+func dominantField(fields []field) (field, bool) {
+	if len(fields) == 1 {
+		return fields[0], true
+	}
+
+	depth := slices.MinFunc(fields, func(a, b field) int {
+		return cmp.Compare(a.depth, b.depth)
+	}).depth
+
+	var winner []field
+	for _, f := range fields {
+		if f.depth == depth {
+			winner = append(winner, f)
+		}
+	}
+
+	if len(winner) > 1 {
+		tagged := winner[:0:0]
+		for _, f := range winner {
+			if f.tagged {
+				tagged = append(tagged, f)
+			}
+		}
+		if len(tagged) > 0 {
+			winner = tagged
+		}
+	}
+
+	if len(winner) != 1 {
+		return field{}, false
+	}
+
+	return winner[0], true
+}
+
+// v.FieldByIndex that reports a nil embedded pointer on the way instead of panicking
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// what omitempty skips: false, zero numbers, nil pointers and interfaces, and
+// empty strings, arrays, slices and maps
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return false
+}
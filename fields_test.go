@@ -0,0 +1,146 @@
+package decodebencode_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+type Common struct {
+	Comment   string `bencode:"comment,omitempty"`
+	CreatedBy string `bencode:"created by,omitempty"`
+}
+
+type Tracker struct {
+	Announce string `bencode:"announce"`
+}
+
+type Timestamps struct {
+	Created int64 `bencode:"creation date"`
+}
+
+type torrentFile struct {
+	Common
+	*Tracker
+	Name     string     `bencode:"name,required"`
+	Length   int        `bencode:"length,omitempty"`
+	Files    []string   `bencode:"files,omitempty"`
+	Private  *int       `bencode:"private,omitempty"`
+	Cache    string     `bencode:"-"`
+	Dash     string     `bencode:"-,"`
+	Times    Timestamps `bencode:",inline"`
+	Embedded Common     `bencode:"embedded,omitempty"`
+}
+
+// Name on depth 0 wins over Name of embedded struct, Both is ambiguous
+type conflictA struct {
+	Name string
+	Both string
+	Tag  string
+}
+
+type conflictB struct {
+	Both   string
+	Tagged string `bencode:"Tag"`
+}
+
+type conflicting struct {
+	conflictA
+	conflictB
+	Name string
+}
+
+func TestMarshalStructTags(t *testing.T) {
+	type TestCase struct {
+		name     string
+		input    any
+		expected string
+	}
+
+	private := 1
+
+	testCases := []TestCase{
+		{
+			name:     "empty fields are omitted, nil embedded pointer is skipped",
+			input:    torrentFile{Name: "a", Cache: "x", Times: Timestamps{Created: 7}},
+			expected: "d1:-0:13:creation datei7e8:embeddedde4:name1:ae",
+		},
+		{
+			name: "all fields set",
+			input: torrentFile{
+				Common:   Common{Comment: "hi", CreatedBy: "me"},
+				Tracker:  &Tracker{Announce: "http://tracker"},
+				Name:     "a",
+				Length:   3,
+				Files:    []string{"f"},
+				Private:  &private,
+				Dash:     "d",
+				Embedded: Common{Comment: "inner"},
+			},
+			expected: "d1:-1:d8:announce14:http://tracker7:comment2:hi10:created by2:me13:creation datei0e" +
+				"8:embeddedd7:comment5:innere5:filesl1:fe6:lengthi3e4:name1:a7:privatei1ee",
+		},
+		{
+			name:     "conflicting names",
+			input:    conflicting{conflictA: conflictA{Name: "inner", Both: "a", Tag: "untagged"}, conflictB: conflictB{Both: "b", Tagged: "tagged"}, Name: "outer"},
+			expected: "d4:Name5:outer3:Tag6:taggede",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := decodebencode.Marshal(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(output) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestUnmarshalStructTags(t *testing.T) {
+	input := "d1:-1:d8:announce14:http://tracker7:comment2:hi13:creation datei9e5:Cache1:x4:name1:a7:privatei1e7:unknowni1ee"
+
+	var output torrentFile
+	if err := decodebencode.Unmarshal([]byte(input), &output); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	private := 1
+	expected := torrentFile{
+		Common:  Common{Comment: "hi"},
+		Tracker: &Tracker{Announce: "http://tracker"},
+		Name:    "a",
+		Private: &private,
+		Dash:    "d",
+		Times:   Timestamps{Created: 9},
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, output)
+	}
+
+	err := decodebencode.Unmarshal([]byte("d6:lengthi3ee"), &output)
+
+	var requiredErr *decodebencode.RequiredKeyError
+	if !errors.As(err, &requiredErr) {
+		t.Fatalf("Expected *RequiredKeyError, got %v", err)
+	}
+	if requiredErr.Key != "name" {
+		t.Errorf("Expected missing key name, got %q", requiredErr.Key)
+	}
+
+	var nested struct {
+		Files []torrentFile `bencode:"files"`
+	}
+	err = decodebencode.Unmarshal([]byte("d5:filesld4:name1:aed6:lengthi1eeee"), &nested)
+	if !errors.As(err, &requiredErr) {
+		t.Fatalf("Expected *RequiredKeyError, got %v", err)
+	}
+	if requiredErr.Path.String() != "files[1]" {
+		t.Errorf("Expected path files[1], got %q", requiredErr.Path)
+	}
+}
@@ -0,0 +1,50 @@
+package decodebencode
+
+// InternTable hands out a single shared copy of every string it has seen, so
+// decoding many small messages with the same keys doesn't allocate them again
+// and again. It is not safe for concurrent use.
+type InternTable struct {
+	strings    map[string]string
+	maxLen     int
+	maxEntries int
+}
+
+// Dict keys are always interned, other strings only when they are at most
+// maxLen bytes long. Once the table holds maxEntries strings new ones are no
+// longer remembered, so hostile input cannot grow it without bound.
+func NewInternTable(maxLen int, maxEntries int) *InternTable {
+	return &InternTable{
+		strings:    make(map[string]string),
+		maxLen:     maxLen,
+		maxEntries: maxEntries,
+	}
+}
+
+// Returns the shared copy of s, remembering s if it is new
+func (t *InternTable) Intern(s string) string {
+	if shared, ok := t.strings[s]; ok {
+		return shared
+	}
+	if len(t.strings) < t.maxEntries {
+		t.strings[s] = s
+	}
+	return s
+}
+
+// number of strings in the table
+func (t *InternTable) Len() int {
+	return len(t.strings)
+}
+
+func internInput[T bencodeInput](t *InternTable, raw T, isKey bool) string {
+	if !isKey && len(raw) > t.maxLen {
+		return string(raw)
+	}
+
+	// lookup with string(raw) doesn't allocate
+	if shared, ok := t.strings[string(raw)]; ok {
+		return shared
+	}
+
+	return t.Intern(string(raw))
+}
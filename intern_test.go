@@ -0,0 +1,89 @@
+package decodebencode_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+// KRPC ping query as sent between DHT nodes
+const krpcPing = "d1:ad2:id20:abcdefghij0123456789e1:q4:ping1:t2:aa1:y1:qe"
+
+func TestInternTable(t *testing.T) {
+	table := decodebencode.NewInternTable(8, 2)
+
+	first := table.Intern(strings.Clone("id"))
+	second := table.Intern(strings.Clone("id"))
+
+	if unsafe.StringData(first) != unsafe.StringData(second) {
+		t.Errorf("Expected interned strings to share memory")
+	}
+
+	table.Intern("q")
+	table.Intern("t")
+
+	if table.Len() != 2 {
+		t.Errorf("Expected table to stop growing at 2 entries, got %d", table.Len())
+	}
+}
+
+func TestDecoderInternTable(t *testing.T) {
+	table := decodebencode.NewInternTable(4, 1024)
+
+	var outputs []map[string]interface{}
+	for range 2 {
+		decoder := decodebencode.NewDecoder(strings.NewReader(krpcPing))
+		decoder.SetInternTable(table)
+
+		output, err := decoder.Decode()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		plain, _ := decodebencode.DecodeBencode(krpcPing)
+		if !reflect.DeepEqual(output, plain) {
+			t.Fatalf("Expected %v, got %v", plain, output)
+		}
+
+		outputs = append(outputs, output.(map[string]interface{}))
+	}
+
+	// the 20 bytes node id is longer than 4 bytes and is not a key
+	expected := []string{"a", "id", "q", "t", "y", "ping", "aa"}
+	if table.Len() != len(expected) {
+		t.Errorf("Expected %d interned strings, got %d", len(expected), table.Len())
+	}
+
+	first := outputs[0]["q"].(string)
+	second := outputs[1]["q"].(string)
+	if unsafe.StringData(first) != unsafe.StringData(second) {
+		t.Errorf("Expected short values of two messages to share memory")
+	}
+}
+
+func BenchmarkDecodeKRPC(b *testing.B) {
+	b.Run("without intern table", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			decoder := decodebencode.NewDecoder(strings.NewReader(krpcPing))
+			if _, err := decoder.Decode(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("with intern table", func(b *testing.B) {
+		table := decodebencode.NewInternTable(8, 1024)
+		b.ReportAllocs()
+		for b.Loop() {
+			decoder := decodebencode.NewDecoder(strings.NewReader(krpcPing))
+			decoder.SetInternTable(table)
+			if _, err := decoder.Decode(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
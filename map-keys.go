@@ -0,0 +1,110 @@
+package decodebencode
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var (
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+func isByteArray(t reflect.Type) bool {
+	return t.Kind() == reflect.Array && t.Elem().Kind() == reflect.Uint8
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// whether keys of type t can be written as dict keys
+func isEncodableKeyType(t reflect.Type) bool {
+	return t.Kind() == reflect.String || t.Implements(textMarshalerType) || isByteArray(t) || isIntegerKind(t.Kind())
+}
+
+// whether dict keys can be stored in map keys of type t
+func isDecodableKeyType(t reflect.Type) bool {
+	return t.Kind() == reflect.String || reflect.PointerTo(t).Implements(textUnmarshalerType) || isByteArray(t) || isIntegerKind(t.Kind())
+}
+
+// raw bytes of the dict key for map key k
+func encodeMapKey(k reflect.Value) (string, error) {
+	t := k.Type()
+
+	switch {
+	case t.Kind() == reflect.String:
+		return k.String(), nil
+
+	case t.Implements(textMarshalerType):
+		if (k.Kind() == reflect.Pointer || k.Kind() == reflect.Interface) && k.IsNil() {
+			return "", &UnsupportedValueError{Value: k, Str: "nil " + t.String() + " map key"}
+		}
+		text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return "", &MarshalerError{Type: t, Err: err, sourceFunc: "MarshalText"}
+		}
+		return string(text), nil
+
+	case isByteArray(t):
+		addressable := reflect.New(t).Elem()
+		addressable.Set(k)
+		return string(addressable.Bytes()), nil
+
+	case k.CanInt():
+		return strconv.FormatInt(k.Int(), 10), nil
+
+	case k.CanUint():
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+
+	return "", &UnsupportedTypeError{Type: t}
+}
+
+// map key of type t for dict key
+func decodeMapKey(key string, t reflect.Type) (reflect.Value, error) {
+	k := reflect.New(t).Elem()
+	typeError := &UnmarshalTypeError{Value: fmt.Sprintf("dict key %q", key), Type: t}
+
+	switch {
+	case t.Kind() == reflect.String:
+		k.SetString(key)
+
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		if err := k.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return k, fmt.Errorf("bencode: cannot unmarshal dict key %q into %s: %w", key, t, err)
+		}
+
+	case isByteArray(t):
+		if len(key) != t.Len() {
+			return k, typeError
+		}
+		reflect.Copy(k, reflect.ValueOf(key))
+
+	case k.CanInt():
+		n, err := strconv.ParseInt(key, 10, 64)
+		if err != nil || k.OverflowInt(n) {
+			return k, typeError
+		}
+		k.SetInt(n)
+
+	case k.CanUint():
+		n, err := strconv.ParseUint(key, 10, 64)
+		if err != nil || k.OverflowUint(n) {
+			return k, typeError
+		}
+		k.SetUint(n)
+
+	default:
+		return k, typeError
+	}
+
+	return k, nil
+}
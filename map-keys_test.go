@@ -0,0 +1,118 @@
+package decodebencode_test
+
+import (
+	"encoding"
+	"errors"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+type peerID string
+
+// key type encoding itself as upper case text
+type upperKey struct {
+	name string
+}
+
+func (k upperKey) MarshalText() ([]byte, error) {
+	if k.name == "" {
+		return nil, errors.New("empty key")
+	}
+	return []byte(strings.ToUpper(k.name)), nil
+}
+
+func (k *upperKey) UnmarshalText(text []byte) error {
+	k.name = strings.ToLower(string(text))
+	return nil
+}
+
+func TestMarshalMapKeys(t *testing.T) {
+	type TestCase struct {
+		name      string
+		input     any
+		expected  string
+		expectErr bool
+	}
+
+	testCases := []TestCase{
+		{name: "custom string type", input: map[peerID]int{"b": 2, "a": 1}, expected: "d1:ai1e1:bi2ee"},
+		{
+			name:     "byte array keys sorted by raw bytes",
+			input:    map[[2]byte]string{{0xff, 0}: "high", {0, 0xff}: "low"},
+			expected: "d2:\x00\xff3:low2:\xff\x004:highe",
+		},
+		{name: "text marshaler", input: map[upperKey]int{{name: "b"}: 1, {name: "a"}: 2}, expected: "d1:Ai2e1:Bi1ee"},
+		{name: "netip address", input: map[netip.Addr]int{netip.MustParseAddr("10.0.0.1"): 1}, expected: "d8:10.0.0.1i1ee"},
+		{name: "integers sorted as text", input: map[int]string{10: "ten", 9: "nine", -1: "minus"}, expected: "d2:-15:minus2:103:ten1:94:ninee"},
+		{name: "unsigned integers", input: map[uint8]int{255: 1}, expected: "d3:255i1ee"},
+		{name: "text marshaler error", input: map[upperKey]int{{}: 1}, expectErr: true},
+		{name: "keys encoded to the same bytes", input: map[upperKey]int{{name: "a"}: 1, {name: "A"}: 2}, expectErr: true},
+		{name: "bool keys", input: map[bool]int{true: 1}, expectErr: true},
+		{name: "struct keys", input: map[struct{ A int }]int{{A: 1}: 1}, expectErr: true},
+		{name: "nil interface key", input: map[encoding.TextMarshaler]int{nil: 1}, expectErr: true},
+		{name: "nil pointer key", input: map[*big.Int]int{nil: 1}, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := decodebencode.Marshal(tc.input)
+			if tc.expectErr {
+				var valueErr *decodebencode.UnsupportedValueError
+				if err == nil {
+					t.Errorf("Expected error, got nil, result: %q", output)
+				} else if strings.HasPrefix(tc.name, "nil") && !errors.As(err, &valueErr) {
+					t.Errorf("Expected *UnsupportedValueError, got %v", err)
+				}
+				if size := decodebencode.EncodedLen(tc.input); size != -1 {
+					t.Errorf("Expected EncodedLen -1, got %d", size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(output) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestUnmarshalMapKeys(t *testing.T) {
+	var hashes map[[2]byte]string
+	if err := decodebencode.Unmarshal([]byte("d2:\x00\xff3:low2:\xff\x004:highe"), &hashes); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(hashes, map[[2]byte]string{{0xff, 0}: "high", {0, 0xff}: "low"}) {
+		t.Errorf("Unexpected result %v", hashes)
+	}
+
+	var upper map[upperKey]int
+	if err := decodebencode.Unmarshal([]byte("d1:Ai2e1:Bi1ee"), &upper); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(upper, map[upperKey]int{{name: "b"}: 1, {name: "a"}: 2}) {
+		t.Errorf("Unexpected result %v", upper)
+	}
+
+	var numbers map[int8]string
+	if err := decodebencode.Unmarshal([]byte("d2:-15:minus1:94:ninee"), &numbers); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(numbers, map[int8]string{-1: "minus", 9: "nine"}) {
+		t.Errorf("Unexpected result %v", numbers)
+	}
+
+	if err := decodebencode.Unmarshal([]byte("d3:300i1ee"), &numbers); err == nil {
+		t.Errorf("Expected error for key overflowing int8")
+	}
+
+	if err := decodebencode.Unmarshal([]byte("d1:xi1ee"), &hashes); err == nil {
+		t.Errorf("Expected error for key of wrong length")
+	}
+}
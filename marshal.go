@@ -0,0 +1,514 @@
+package decodebencode
+
+import (
+	"cmp"
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+)
+
+// Returned by Marshal for values that have no bencode representation. Path
+// points to the value inside the one given to Marshal.
+type UnsupportedTypeError struct {
+	Type reflect.Type
+	Path Path
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return "bencode: unsupported type " + e.Type.String() + atPath(e.Path)
+}
+
+// Returned by Marshal for values of a supported type that still cannot be
+// encoded. Path points to the value inside the one given to Marshal.
+type UnsupportedValueError struct {
+	Value reflect.Value
+	Str   string
+	Path  Path
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return "bencode: unsupported value " + e.Str + atPath(e.Path)
+}
+
+// Implemented by types that encode themselves. MarshalBencode must return
+// exactly one valid bencode value, it is written to the output as is.
+type Marshaler interface {
+	MarshalBencode() ([]byte, error)
+}
+
+var marshalerType = reflect.TypeFor[Marshaler]()
+
+// Returned when MarshalBencode fails or returns invalid bencode, or when
+// MarshalBinary or MarshalText fails
+type MarshalerError struct {
+	Type reflect.Type
+	Err  error
+	Path Path
+	// method that failed, MarshalBencode when empty
+	sourceFunc string
+}
+
+func (e *MarshalerError) Error() string {
+	sourceFunc := e.sourceFunc
+	if sourceFunc == "" {
+		sourceFunc = "MarshalBencode"
+	}
+	return "bencode: error calling " + sourceFunc + " for type " + e.Type.String() + atPath(e.Path) + ": " + e.Err.Error()
+}
+
+func (e *MarshalerError) Unwrap() error {
+	return e.Err
+}
+
+func atPath(path Path) string {
+	if len(path) == 0 {
+		return ""
+	}
+	return " at `" + path.String() + "`"
+}
+
+// Puts segment in front of the path of an encoding or decoding error, paths are built
+// while recursion unwinds
+func prependPath(err error, segment PathSegment) error {
+	switch e := err.(type) {
+	case *UnsupportedTypeError:
+		e.Path = append(Path{segment}, e.Path...)
+	case *UnsupportedValueError:
+		e.Path = append(Path{segment}, e.Path...)
+	case *MarshalerError:
+		e.Path = append(Path{segment}, e.Path...)
+	case *UnmarshalerError:
+		e.Path = append(Path{segment}, e.Path...)
+	case *ConverterError:
+		e.Path = append(Path{segment}, e.Path...)
+	case *CycleError:
+		e.Path = append(Path{segment}, e.Path...)
+	case *UnmarshalTypeError:
+		e.Path = append(Path{segment}, e.Path...)
+	case *RequiredKeyError:
+		e.Path = append(Path{segment}, e.Path...)
+	}
+	return err
+}
+
+// Marshal returns bencode of v, following the rules of encoding/json where
+// bencode allows it.
+//
+// Integers of every width become bencode integers. Strings, byte slices and
+// byte arrays become byte strings. Other slices and arrays become lists, nil
+// slice is an empty list. Maps and structs become dictionaries with keys
+// sorted by their raw bytes. Map keys of string kinds are used as they are,
+// then encoding.TextMarshaler, byte arrays such as [20]byte infohashes give
+// their raw bytes and integers are written in decimal, maps with other key
+// types are rejected.
+//
+// Struct fields are named with the `bencode` struct tag:
+//
+//	Name string `bencode:"name"`           // key "name"
+//	Size int    `bencode:"size,omitempty"` // skipped when zero
+//	Hash []byte `bencode:",required"`      // Unmarshal fails without key "Hash"
+//	Temp string `bencode:"-"`              // never encoded nor decoded
+//	Dash string `bencode:"-,"`             // key "-"
+//	Meta Meta   `bencode:",inline"`        // fields of Meta are flattened
+//
+// Unexported fields are skipped. Fields of embedded structs without a name in
+// the tag are flattened into the outer dictionary. When several fields end up
+// with the same key, the least nested one wins, then the one named by a tag;
+// if that is still ambiguous none of them is used.
+//
+// Pointers and interfaces are encoded as the value they point to. Values
+// implementing Marshaler encode themselves, time, duration and IP values are
+// encoded by the converters registered for them, see converters.go. Other
+// values implementing encoding.BinaryMarshaler or, failing that,
+// encoding.TextMarshaler become byte strings of what these return.
+//
+// Bools, floats, complex numbers, channels, functions and nil pointers are
+// rejected with an error, EncodeOptions.Marshal can encode some of them.
+// Values that contain themselves fail with *CycleError, lists and dicts nested
+// deeper than 10000 levels with *MaxDepthError.
+func Marshal(v any) ([]byte, error) {
+	e := encodeState{}
+
+	if err := e.marshal(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+
+	return e.buf, nil
+}
+
+// once that many bytes are buffered they are written out to encodeState.w
+const encodeBufferSize = 4096
+
+// Output of a Marshal or Encoder.Encode call. Without a writer all output is
+// collected in buf, with one buf is flushed to w whenever it grows past
+// encodeBufferSize. The first write error sticks in err. With sizeOnly
+// nothing is written, only the size of the output is counted.
+type encodeState struct {
+	buf      []byte
+	w        io.Writer
+	err      error
+	sizeOnly bool
+	size     int64
+	opts     EncodeOptions
+	// lists and dicts open
+	depth int
+	// pointers, maps and slices being encoded, see enterReference
+	ptrLevel int
+	ptrSeen  map[any]struct{}
+}
+
+func (e *encodeState) flush() error {
+	if e.w == nil || e.err != nil || len(e.buf) == 0 {
+		return e.err
+	}
+
+	_, e.err = e.w.Write(e.buf)
+	e.buf = e.buf[:0]
+	return e.err
+}
+
+func (e *encodeState) flushIfFull() {
+	if e.w != nil && len(e.buf) >= encodeBufferSize {
+		e.flush()
+	}
+}
+
+func (e *encodeState) writeByte(c byte) {
+	if e.sizeOnly {
+		e.size++
+		return
+	}
+	e.buf = append(e.buf, c)
+	e.flushIfFull()
+}
+
+func (e *encodeState) writeInt(i int64) {
+	if e.sizeOnly {
+		e.size += intLen(i)
+		return
+	}
+	e.buf = AppendInt(e.buf, i)
+	e.flushIfFull()
+}
+
+func (e *encodeState) writeUint(u uint64) {
+	if e.sizeOnly {
+		e.size += uintLen(u)
+		return
+	}
+	e.buf = AppendUint(e.buf, u)
+	e.flushIfFull()
+}
+
+func (e *encodeState) writeString(s string) {
+	if e.sizeOnly {
+		e.size += stringLen(len(s))
+		return
+	}
+	e.buf = appendLength(e.buf, len(s))
+
+	// big strings go straight to the writer, without a copy in buf
+	if e.w != nil && len(s) >= encodeBufferSize {
+		if e.flush() == nil {
+			_, e.err = io.WriteString(e.w, s)
+		}
+		return
+	}
+
+	e.buf = append(e.buf, s...)
+	e.flushIfFull()
+}
+
+func (e *encodeState) writeBytes(b []byte) {
+	if e.sizeOnly {
+		e.size += stringLen(len(b))
+		return
+	}
+	e.buf = appendLength(e.buf, len(b))
+	e.writeRaw(b)
+}
+
+// writes already encoded bencode
+func (e *encodeState) writeRaw(b []byte) {
+	if e.sizeOnly {
+		e.size += int64(len(b))
+		return
+	}
+	if e.w != nil && len(b) >= encodeBufferSize {
+		if e.flush() == nil {
+			_, e.err = e.w.Write(b)
+		}
+		return
+	}
+
+	e.buf = append(e.buf, b...)
+	e.flushIfFull()
+}
+
+func (e *encodeState) marshal(v reflect.Value) error {
+	if !v.IsValid() {
+		return e.marshalNil(v)
+	}
+
+	if marshaler, ok := asMarshaler(v); ok {
+		return e.marshalMarshaler(v, marshaler)
+	}
+
+	if c, ok := converterFor(v.Type()); ok {
+		return e.marshalConverted(v, c)
+	}
+
+	// pointers are followed first, so that converters of the values they
+	// point to win over methods promoted to the pointer
+	if v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface {
+		if marshaler, ok := implementation[encoding.BinaryMarshaler](v); ok {
+			return e.marshalBinary(v, marshaler)
+		}
+
+		if marshaler, ok := implementation[encoding.TextMarshaler](v); ok {
+			return e.marshalText(v, marshaler)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.writeInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.writeUint(v.Uint())
+	case reflect.String:
+		e.writeString(v.String())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.writeBytes(v.Bytes())
+			return nil
+		}
+		return e.marshalList(v)
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			e.marshalByteArray(v)
+			return nil
+		}
+		return e.marshalList(v)
+	case reflect.Map:
+		return e.marshalMap(v)
+	case reflect.Struct:
+		return e.marshalStruct(v)
+	case reflect.Bool:
+		return e.marshalBool(v)
+	case reflect.Float32, reflect.Float64:
+		return e.marshalFloat(v)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return e.marshalNil(v)
+		}
+		if v.Kind() == reflect.Interface {
+			return e.marshal(v.Elem())
+		}
+		return e.marshalPointer(v)
+	default:
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+
+	return nil
+}
+
+// Marshaler implemented by v or, when v is addressable, by its pointer. Nil
+// pointers are left to the caller, they cannot encode themselves.
+func asMarshaler(v reflect.Value) (Marshaler, bool) {
+	return implementation[Marshaler](v)
+}
+
+// Interface I implemented by v or, when v is addressable, by its pointer. Nil
+// pointers and interfaces don't count.
+func implementation[I any](v reflect.Value) (I, bool) {
+	var none I
+	iface := reflect.TypeFor[I]()
+
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return none, false
+	}
+
+	if v.Type().Implements(iface) {
+		if v.Kind() == reflect.Interface && v.IsNil() {
+			return none, false
+		}
+		return v.Interface().(I), true
+	}
+
+	if v.Kind() != reflect.Pointer && v.CanAddr() && reflect.PointerTo(v.Type()).Implements(iface) {
+		return v.Addr().Interface().(I), true
+	}
+
+	return none, false
+}
+
+func (e *encodeState) marshalMarshaler(v reflect.Value, marshaler Marshaler) error {
+	b, err := marshaler.MarshalBencode()
+
+	if err == nil {
+		err = checkMarshalerOutput(marshaler, b)
+	}
+
+	if err != nil {
+		return &MarshalerError{Type: v.Type(), Err: err}
+	}
+
+	e.writeRaw(b)
+	return nil
+}
+
+// RawValue keeps whatever Unmarshal accepted, so it is checked against what
+// the decoder accepts instead of the canonical form
+func checkMarshalerOutput(marshaler Marshaler, b []byte) error {
+	switch marshaler.(type) {
+	case RawValue, *RawValue:
+		return checkDecodable(b)
+	}
+	return checkValid(b)
+}
+
+func (e *encodeState) marshalByteArray(v reflect.Value) {
+	if e.sizeOnly {
+		e.size += stringLen(v.Len())
+		return
+	}
+
+	if v.CanAddr() {
+		e.writeBytes(v.Bytes())
+		return
+	}
+
+	e.buf = appendLength(e.buf, v.Len())
+	for i := range v.Len() {
+		e.buf = append(e.buf, byte(v.Index(i).Uint()))
+	}
+	e.flushIfFull()
+}
+
+func (e *encodeState) marshalPointer(v reflect.Value) error {
+	key, err := e.enterReference(v)
+	if err != nil {
+		return err
+	}
+	defer e.leaveReference(key)
+
+	return e.marshal(v.Elem())
+}
+
+func (e *encodeState) marshalList(v reflect.Value) error {
+	if err := e.enterContainer(); err != nil {
+		return err
+	}
+	defer e.leaveContainer()
+
+	if v.Kind() == reflect.Slice {
+		key, err := e.enterReference(v)
+		if err != nil {
+			return err
+		}
+		defer e.leaveReference(key)
+	}
+
+	e.writeByte(LIST_CONTROL_SYMBOL)
+
+	for i := 0; i < v.Len() && e.err == nil; i++ {
+		if e.skips(v.Index(i)) {
+			continue
+		}
+		if err := e.marshal(v.Index(i)); err != nil {
+			return prependPath(err, PathSegment{Index: i, IsIndex: true})
+		}
+	}
+
+	e.writeByte(CLOSE_CONTROL_SYMBOL)
+	return nil
+}
+
+func (e *encodeState) marshalMap(v reflect.Value) error {
+	if !isEncodableKeyType(v.Type().Key()) {
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+
+	if err := e.enterContainer(); err != nil {
+		return err
+	}
+	defer e.leaveContainer()
+
+	key, err := e.enterReference(v)
+	if err != nil {
+		return err
+	}
+	defer e.leaveReference(key)
+
+	type entry struct {
+		key   string
+		value reflect.Value
+	}
+
+	entries := make([]entry, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		key, err := encodeMapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: key, value: iter.Value()})
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		return cmp.Compare(a.key, b.key)
+	})
+
+	for i := 1; i < len(entries); i++ {
+		if entries[i].key == entries[i-1].key {
+			return &UnsupportedValueError{Value: v, Str: fmt.Sprintf("map with several keys encoded as %q", entries[i].key)}
+		}
+	}
+
+	e.writeByte(DICT_CONTROL_SYMBOL)
+
+	for _, entry := range entries {
+		if e.err != nil {
+			break
+		}
+		if e.skips(entry.value) {
+			continue
+		}
+		e.writeString(entry.key)
+		if err := e.marshal(entry.value); err != nil {
+			return prependPath(err, PathSegment{Key: entry.key})
+		}
+	}
+
+	e.writeByte(CLOSE_CONTROL_SYMBOL)
+	return nil
+}
+
+func (e *encodeState) marshalStruct(v reflect.Value) error {
+	if err := e.enterContainer(); err != nil {
+		return err
+	}
+	defer e.leaveContainer()
+
+	e.writeByte(DICT_CONTROL_SYMBOL)
+
+	for _, f := range cachedFields(v.Type()) {
+		if e.err != nil {
+			break
+		}
+
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(fv)) || e.skips(fv) {
+			continue
+		}
+
+		e.writeString(f.name)
+		if err := e.marshal(fv); err != nil {
+			return prependPath(err, PathSegment{Key: f.name})
+		}
+	}
+
+	e.writeByte(CLOSE_CONTROL_SYMBOL)
+	return nil
+}
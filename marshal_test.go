@@ -0,0 +1,243 @@
+package decodebencode_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+type marshalFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type marshalInfo struct {
+	Name        string        `bencode:"name"`
+	PieceLength uint32        `bencode:"piece length"`
+	Pieces      []byte        `bencode:"pieces"`
+	Files       []marshalFile `bencode:"files"`
+	Private     *int8         `bencode:"private"`
+	Untagged    string
+	unexported  string
+}
+
+type infohash [20]byte
+
+func TestMarshal(t *testing.T) {
+	type TestCase struct {
+		name      string
+		input     any
+		expected  string
+		expectErr bool
+	}
+
+	one := int8(1)
+	var answer any = 42
+	hash := infohash{'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 'k', 'l', 'm', 'n', 'o', 'p', 'q', 'r', 's', 't'}
+
+	testCases := []TestCase{
+		{name: "int", input: 42, expected: "i42e"},
+		{name: "int8", input: int8(-8), expected: "i-8e"},
+		{name: "int64", input: int64(-1 << 62), expected: "i-4611686018427387904e"},
+		{name: "uint16", input: uint16(65535), expected: "i65535e"},
+		{name: "uint64 above int64", input: uint64(1 << 63), expected: "i9223372036854775808e"},
+		{name: "string", input: "hi!", expected: "3:hi!"},
+		{name: "empty string", input: "", expected: "0:"},
+		{name: "unicode string", input: "ゴゴゴゴ", expected: "12:ゴゴゴゴ"},
+		{name: "byte slice", input: []byte{0, 1, 255}, expected: "3:\x00\x01\xff"},
+		{name: "byte array", input: hash, expected: "20:abcdefghijklmnopqrst"},
+		{name: "pointer to byte array", input: &hash, expected: "20:abcdefghijklmnopqrst"},
+		{name: "string slice", input: []string{"a", "bc"}, expected: "l1:a2:bce"},
+		{name: "uint8 array", input: [3]uint8{1, 2, 3}, expected: "3:\x01\x02\x03"},
+		{name: "int32 array", input: [2]int32{1, 2}, expected: "li1ei2ee"},
+		{name: "nil slice", input: []int(nil), expected: "le"},
+		{name: "nested slices", input: [][]int{{1}, {}}, expected: "lli1eelee"},
+		{name: "map of ints", input: map[string]int{"b": 2, "a": 1}, expected: "d1:ai1e1:bi2ee"},
+		{name: "keys sorted by raw bytes", input: map[string]int{"b": 2, "B": 1, "ゴ": 3}, expected: "d1:Bi1e1:bi2e3:ゴi3ee"},
+		{name: "map of any", input: map[string]any{"list": []any{1, "x"}, "n": &one}, expected: "d4:listli1e1:xe1:ni1ee"},
+		{name: "interface", input: &answer, expected: "i42e"},
+		{
+			name: "struct",
+			input: marshalInfo{
+				Name:        "a.txt",
+				PieceLength: 16384,
+				Pieces:      []byte("01234567890123456789"),
+				Files:       []marshalFile{{Length: 7, Path: []string{"dir", "a.txt"}}},
+				Private:     &one,
+				Untagged:    "x",
+				unexported:  "skipped",
+			},
+			expected: "d8:Untagged1:x5:filesld6:lengthi7e4:pathl3:dir5:a.txteee4:name5:a.txt12:piece lengthi16384e6:pieces20:012345678901234567897:privatei1ee",
+		},
+		{name: "nil", input: nil, expectErr: true},
+		{name: "nil pointer", input: (*int)(nil), expectErr: true},
+		{name: "bool", input: true, expectErr: true},
+		{name: "float", input: 1.5, expectErr: true},
+		{name: "func", input: func() {}, expectErr: true},
+		{name: "channel in list", input: []any{1, make(chan int)}, expectErr: true},
+		{name: "map with float keys", input: map[float64]string{1: "a"}, expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := decodebencode.Marshal(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil, result: %q", output)
+				}
+				if output != nil {
+					t.Errorf("Expected nil result, got %q", output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(output) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestMarshalErrorTypes(t *testing.T) {
+	_, err := decodebencode.Marshal(map[string]any{"ok": 1, "price": 9.99})
+
+	var typeErr *decodebencode.UnsupportedTypeError
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected *UnsupportedTypeError, got %v", err)
+	}
+	if typeErr.Type != reflect.TypeOf(9.99) {
+		t.Errorf("Expected error about float64, got %v", typeErr.Type)
+	}
+
+	_, err = decodebencode.Marshal([]*int{nil})
+
+	var valueErr *decodebencode.UnsupportedValueError
+	if !errors.As(err, &valueErr) {
+		t.Fatalf("Expected *UnsupportedValueError, got %v", err)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	input := map[string]any{
+		"answer": 42,
+		"list":   []any{1, "hi", map[string]any{"jojo": "ゴゴゴゴ"}},
+		"bytes":  "\x00\xff",
+	}
+
+	encoded, err := decodebencode.Marshal(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded, err := decodebencode.DecodeBencode(string(encoded))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded, input) {
+		t.Errorf("Expected %v, got %v", input, decoded)
+	}
+}
+
+// compact IPv4 peers, 6 bytes each
+type compactPeers [][6]byte
+
+func (p compactPeers) MarshalBencode() ([]byte, error) {
+	compact := make([]byte, 0, 6*len(p))
+	for _, peer := range p {
+		compact = append(compact, peer[:]...)
+	}
+	return decodebencode.Marshal(compact)
+}
+
+// encoded as a list of flags that are set, implemented on the pointer
+type bitfield struct {
+	bits uint8
+}
+
+func (b *bitfield) MarshalBencode() ([]byte, error) {
+	set := []int{}
+	for i := range 8 {
+		if b.bits&(1<<i) != 0 {
+			set = append(set, i)
+		}
+	}
+	return decodebencode.Marshal(set)
+}
+
+type brokenMarshaler string
+
+func (b brokenMarshaler) MarshalBencode() ([]byte, error) {
+	if b == "" {
+		return nil, errors.New("nothing to encode")
+	}
+	return []byte(b), nil
+}
+
+func TestMarshaler(t *testing.T) {
+	type TestCase struct {
+		name      string
+		input     any
+		expected  string
+		expectErr bool
+	}
+
+	peers := compactPeers{{127, 0, 0, 1, 0x1a, 0xe1}}
+
+	testCases := []TestCase{
+		{name: "value receiver", input: peers, expected: "6:\x7f\x00\x00\x01\x1a\xe1"},
+		{name: "inside dict", input: map[string]any{"peers": peers, "interval": 1800}, expected: "d8:intervali1800e5:peers6:\x7f\x00\x00\x01\x1a\xe1e"},
+		{name: "pointer receiver", input: &bitfield{bits: 0b101}, expected: "li0ei2ee"},
+		{name: "pointer receiver on addressable slice element", input: []bitfield{{bits: 0b10}}, expected: "lli1eee"},
+		{name: "verbatim output", input: brokenMarshaler("d1:bi1e1:ai2ee"), expected: "d1:bi1e1:ai2ee"},
+		{name: "invalid output", input: []any{brokenMarshaler("i03e")}, expectErr: true},
+		{name: "trailing output", input: brokenMarshaler("i1ei2e"), expectErr: true},
+		{name: "method error", input: map[string]any{"x": brokenMarshaler("")}, expectErr: true},
+		{name: "nil pointer", input: (*bitfield)(nil), expectErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := decodebencode.Marshal(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("Expected error, got nil, result: %q", output)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if string(output) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestMarshalerError(t *testing.T) {
+	_, err := decodebencode.EncodeBencodeDict(map[string]any{"list": []any{brokenMarshaler("")}})
+
+	var marshalerErr *decodebencode.MarshalerError
+	if !errors.As(err, &marshalerErr) {
+		t.Fatalf("Expected *MarshalerError, got %v", err)
+	}
+	if marshalerErr.Path.String() != "list[0]" {
+		t.Errorf("Expected path list[0], got %v", marshalerErr.Path)
+	}
+	if marshalerErr.Err.Error() != "nothing to encode" {
+		t.Errorf("Expected error of the method, got %v", marshalerErr.Err)
+	}
+
+	output, err := decodebencode.EncodeBencodeList([]any{compactPeers{{1, 2, 3, 4, 5, 6}}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if output != "l6:\x01\x02\x03\x04\x05\x06e" {
+		t.Errorf("Expected list with compact peers, got %q", output)
+	}
+}
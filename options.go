@@ -0,0 +1,162 @@
+package decodebencode
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// What to do with nil pointers, nil interfaces and untyped nil. Nil slices and
+// maps are not affected, they are empty lists and dicts.
+type NilPolicy int
+
+const (
+	// Fail with *UnsupportedValueError
+	NilError NilPolicy = iota
+	// Leave the dict entry or list element out. A nil that is not inside a
+	// dict or list still fails.
+	NilSkip
+	// Write an empty string
+	NilEmptyString
+)
+
+// What to do with bool values, bencode has no bool type
+type BoolPolicy int
+
+const (
+	// Fail with *UnsupportedTypeError
+	BoolError BoolPolicy = iota
+	// Write i0e and i1e
+	BoolInteger
+)
+
+// What to do with float values, bencode has no float type
+type FloatPolicy int
+
+const (
+	// Fail with *UnsupportedTypeError
+	FloatError FloatPolicy = iota
+	// Write the shortest decimal string that parses back to the same value,
+	// 0.1 becomes 3:0.1. NaN and infinities still fail.
+	FloatString
+)
+
+// Tells how values without a bencode form are encoded. The zero value
+// rejects all of them, which is what Marshal does.
+type EncodeOptions struct {
+	Nil   NilPolicy
+	Bool  BoolPolicy
+	Float FloatPolicy
+	// Most lists and dicts nested in each other, 10000 when not positive
+	MaxDepth int
+}
+
+// Marshal using opts
+func (opts EncodeOptions) Marshal(v any) ([]byte, error) {
+	e := encodeState{opts: opts}
+
+	if err := e.marshal(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+
+	return e.buf, nil
+}
+
+// EncodeBencodeList using opts
+func (opts EncodeOptions) EncodeBencodeList(list []any) (string, error) {
+	e := encodeState{opts: opts}
+
+	if err := e.marshalList(reflect.ValueOf(list)); err != nil {
+		return "", err
+	}
+
+	return string(e.buf), nil
+}
+
+// EncodeBencodeDict using opts
+func (opts EncodeOptions) EncodeBencodeDict(dict map[string]any) (string, error) {
+	e := encodeState{opts: opts}
+
+	if err := e.marshalMap(reflect.ValueOf(dict)); err != nil {
+		return "", err
+	}
+
+	return string(e.buf), nil
+}
+
+// EncodedLen using opts
+func (opts EncodeOptions) EncodedLen(v any) int64 {
+	e := encodeState{sizeOnly: true, opts: opts}
+
+	if err := e.marshal(reflect.ValueOf(v)); err != nil {
+		return -1
+	}
+
+	return e.size
+}
+
+// Sets how the following Encode calls treat nil, bool and float values
+func (enc *Encoder) SetOptions(opts EncodeOptions) {
+	enc.state.opts = opts
+}
+
+// true for untyped nil and for nil pointers and interfaces, followed through
+// pointers and interfaces that are not nil
+func isNil(v reflect.Value) bool {
+	for {
+		if !v.IsValid() {
+			return true
+		}
+		if v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface {
+			return false
+		}
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+}
+
+// whether a dict entry or list element holding v is left out
+func (e *encodeState) skips(v reflect.Value) bool {
+	return e.opts.Nil == NilSkip && isNil(v)
+}
+
+func (e *encodeState) marshalNil(v reflect.Value) error {
+	if e.opts.Nil == NilEmptyString {
+		e.writeString("")
+		return nil
+	}
+
+	if !v.IsValid() {
+		return &UnsupportedValueError{Value: v, Str: "nil"}
+	}
+	return &UnsupportedValueError{Value: v, Str: "nil " + v.Type().String()}
+}
+
+func (e *encodeState) marshalBool(v reflect.Value) error {
+	if e.opts.Bool != BoolInteger {
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+
+	if v.Bool() {
+		e.writeInt(1)
+	} else {
+		e.writeInt(0)
+	}
+	return nil
+}
+
+func (e *encodeState) marshalFloat(v reflect.Value) error {
+	if e.opts.Float != FloatString {
+		return &UnsupportedTypeError{Type: v.Type()}
+	}
+
+	f := v.Float()
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return &UnsupportedValueError{Value: v, Str: strconv.FormatFloat(f, 'g', -1, 64)}
+	}
+
+	e.writeString(strconv.FormatFloat(f, 'f', -1, v.Type().Bits()))
+	return nil
+}
@@ -0,0 +1,163 @@
+package decodebencode_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func TestEncodeOptions(t *testing.T) {
+	type TestCase struct {
+		name     string
+		opts     decodebencode.EncodeOptions
+		input    any
+		expected string
+	}
+
+	var nilPointer *int
+	var nilInterface any
+
+	type optional struct {
+		A *int    `bencode:"a"`
+		B any     `bencode:"b"`
+		C float32 `bencode:"c"`
+		D bool    `bencode:"d"`
+	}
+
+	skip := decodebencode.EncodeOptions{Nil: decodebencode.NilSkip}
+	empty := decodebencode.EncodeOptions{Nil: decodebencode.NilEmptyString}
+	lenient := decodebencode.EncodeOptions{Nil: decodebencode.NilSkip, Bool: decodebencode.BoolInteger, Float: decodebencode.FloatString}
+
+	testCases := []TestCase{
+		{name: "skip nil dict value", opts: skip, input: map[string]any{"a": nil, "b": 1}, expected: "d1:bi1ee"},
+		{name: "skip nil pointer in interface", opts: skip, input: map[string]any{"a": nilPointer}, expected: "de"},
+		{name: "skip nil list element", opts: skip, input: []any{1, nil, &nilInterface, 2}, expected: "li1ei2ee"},
+		{name: "skip nil struct fields", opts: lenient, input: optional{}, expected: "d1:c1:01:di0ee"},
+		{name: "nil slice is still a list", opts: skip, input: map[string]any{"a": []int(nil)}, expected: "d1:alee"},
+		{name: "nil as empty string", opts: empty, input: []any{nil, nilPointer}, expected: "l0:0:e"},
+		{name: "top level nil as empty string", opts: empty, input: nil, expected: "0:"},
+		{name: "bools", opts: lenient, input: []bool{true, false}, expected: "li1ei0ee"},
+		{name: "float64", opts: lenient, input: 0.1, expected: "3:0.1"},
+		{name: "float32", opts: lenient, input: float32(0.1), expected: "3:0.1"},
+		{name: "float without exponent", opts: lenient, input: 1e21, expected: "22:1000000000000000000000"},
+		{name: "negative float", opts: lenient, input: -2.5, expected: "4:-2.5"},
+		{
+			name:     "struct with all of them",
+			opts:     lenient,
+			input:    optional{B: "x", C: 1.5, D: true},
+			expected: "d1:b1:x1:c3:1.51:di1ee",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := tc.opts.Marshal(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if string(output) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestEncodeOptionsErrors(t *testing.T) {
+	type TestCase struct {
+		name  string
+		opts  decodebencode.EncodeOptions
+		input any
+		value bool
+	}
+
+	testCases := []TestCase{
+		{name: "nil by default", input: map[string]any{"a": nil}, value: true},
+		{name: "bool by default", input: map[string]any{"a": true}},
+		{name: "float by default", input: map[string]any{"a": 1.5}},
+		{name: "top level nil with skip", opts: decodebencode.EncodeOptions{Nil: decodebencode.NilSkip}, input: nil, value: true},
+		{name: "NaN", opts: decodebencode.EncodeOptions{Float: decodebencode.FloatString}, input: []any{math.NaN()}, value: true},
+		{name: "infinity", opts: decodebencode.EncodeOptions{Float: decodebencode.FloatString}, input: []any{math.Inf(-1)}, value: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.opts.Marshal(tc.input)
+
+			var typeError *decodebencode.UnsupportedTypeError
+			var valueError *decodebencode.UnsupportedValueError
+
+			if tc.value && !errors.As(err, &valueError) {
+				t.Errorf("Expected *UnsupportedValueError, got %v", err)
+			}
+			if !tc.value && !errors.As(err, &typeError) {
+				t.Errorf("Expected *UnsupportedTypeError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEncodeOptionsDictAndList(t *testing.T) {
+	opts := decodebencode.EncodeOptions{Nil: decodebencode.NilSkip, Bool: decodebencode.BoolInteger}
+
+	dict, err := opts.EncodeBencodeDict(map[string]any{"private": true, "comment": nil})
+	if err != nil || dict != "d7:privatei1ee" {
+		t.Errorf("Expected %q, got %q, %v", "d7:privatei1ee", dict, err)
+	}
+
+	list, err := opts.EncodeBencodeList([]any{nil, false})
+	if err != nil || list != "li0ee" {
+		t.Errorf("Expected %q, got %q, %v", "li0ee", list, err)
+	}
+}
+
+func TestEncoderSetOptions(t *testing.T) {
+	var buf bytes.Buffer
+	enc := decodebencode.NewEncoder(&buf)
+
+	if err := enc.Encode(true); err == nil {
+		t.Errorf("Expected an error for bool without options")
+	}
+
+	enc.SetOptions(decodebencode.EncodeOptions{Bool: decodebencode.BoolInteger})
+	if err := enc.Encode(true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if buf.String() != "i1e" {
+		t.Errorf("Expected %q, got %q", "i1e", buf.String())
+	}
+}
+
+func TestEncodersNeverPanic(t *testing.T) {
+	var nilMap map[string]any
+	var nilFunc func()
+
+	inputs := []any{
+		nil,
+		nilMap,
+		map[string]any{"a": nil, "b": nilMap, "c": nilFunc, "d": make(chan int), "e": complex(1, 2)},
+		[]any{nil, true, 1.5, struct{ F func() }{}},
+		&struct{ P *struct{ Q *int } }{},
+	}
+
+	for _, opts := range []decodebencode.EncodeOptions{
+		{},
+		{Nil: decodebencode.NilSkip, Bool: decodebencode.BoolInteger, Float: decodebencode.FloatString},
+		{Nil: decodebencode.NilEmptyString},
+	} {
+		for _, input := range inputs {
+			opts.Marshal(input)
+			if dict, ok := input.(map[string]any); ok {
+				opts.EncodeBencodeDict(dict)
+			}
+			if list, ok := input.([]any); ok {
+				opts.EncodeBencodeList(list)
+			}
+			decodebencode.EncodedLen(input)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package decodebencode
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Paths are written the way Path.String renders them: keys separated by dots,
+// list indexes in brackets and keys that cannot be written bare quoted in
+// brackets, e.g. `info.files[2].path[0]` or `info["name.utf-8"]`. The empty
+// path is the root value.
+
+// Returned by ParsePath for paths that cannot be parsed
+type PathSyntaxError struct {
+	Path   string
+	Offset int
+	Msg    string
+}
+
+func (e *PathSyntaxError) Error() string {
+	return fmt.Sprintf("bencode: invalid path %q at offset %d: %s", e.Path, e.Offset, e.Msg)
+}
+
+// Reason of a PathError when a key or an index is missing
+var ErrPathNotFound = errors.New("not found")
+
+// Returned by Get, Set and Delete. Path[Failed] is the segment that could not
+// be followed.
+type PathError struct {
+	Op     string
+	Path   Path
+	Failed int
+	Err    error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("bencode: %s `%s`: segment `%s` of `%s`: %v", e.Op, e.Path, e.Path[e.Failed:e.Failed+1], e.Path[:e.Failed+1], e.Err)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// Parses path written the way Path.String renders it
+func ParsePath(path string) (Path, error) {
+	p := Path{}
+	syntaxError := func(offset int, format string, args ...any) (Path, error) {
+		return nil, &PathSyntaxError{Path: path, Offset: offset, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	for i := 0; i < len(path); {
+		switch {
+		case path[i] == '[':
+			closing := strings.IndexByte(path[i:], ']')
+
+			if i+1 < len(path) && path[i+1] == '"' {
+				quoted, err := strconv.QuotedPrefix(path[i+1:])
+				if err != nil {
+					return syntaxError(i+1, "unterminated or malformed quoted key")
+				}
+				key, _ := strconv.Unquote(quoted)
+
+				end := i + 1 + len(quoted)
+				if end >= len(path) || path[end] != ']' {
+					return syntaxError(end, "expected ] after quoted key")
+				}
+				p = append(p, PathSegment{Key: key})
+				i = end + 1
+				break
+			}
+
+			if closing < 0 {
+				return syntaxError(i, "missing ]")
+			}
+
+			digits := path[i+1 : i+closing]
+			index, err := strconv.Atoi(digits)
+			if err != nil || index < 0 || digits[0] == '+' {
+				return syntaxError(i+1, "%q is not a list index", digits)
+			}
+			p = append(p, PathSegment{Index: index, IsIndex: true})
+			i += closing + 1
+
+		case path[i] == '.' && len(p) > 0:
+			i++
+			end := i + strings.IndexAny(path[i:], `.[]"\`)
+			if end < i {
+				end = len(path)
+			}
+			if end == i {
+				return syntaxError(i, "expected key after .")
+			}
+			p = append(p, PathSegment{Key: path[i:end]})
+			i = end
+
+		case len(p) == 0 && !strings.ContainsRune(`.[]"\`, rune(path[i])):
+			end := strings.IndexAny(path, `.[]"\`)
+			if end < 0 {
+				end = len(path)
+			}
+			p = append(p, PathSegment{Key: path[:end]})
+			i = end
+
+		default:
+			return syntaxError(i, "unexpected %q", path[i])
+		}
+	}
+
+	return p, nil
+}
+
+// Value at path inside tree, a value DecodeBencode returned
+func Get(tree interface{}, path string) (interface{}, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, segment := range p {
+		if tree, err = child(tree, segment); err != nil {
+			return nil, &PathError{Op: "get", Path: p, Failed: i, Err: err}
+		}
+	}
+
+	return tree, nil
+}
+
+// Whether path is valid and leads to a value inside tree
+func Exists(tree interface{}, path string) bool {
+	_, err := Get(tree, path)
+	return err == nil
+}
+
+// Puts value at path inside tree and returns the new root. Dicts and lists of
+// tree are changed in place, but lists may move when they grow, so the
+// returned root is the one to use. Missing dict keys are added, with empty
+// dicts created on the way for keys followed by more keys. A list index equal
+// to the length of the list appends to it. The empty path replaces the root.
+func Set(tree interface{}, path string, value interface{}) (interface{}, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	root, failed, err := setIn(tree, p, 0, value)
+	if err != nil {
+		return nil, &PathError{Op: "set", Path: p, Failed: failed, Err: err}
+	}
+
+	return root, nil
+}
+
+// Removes the value at path from tree and returns the new root. Dicts and
+// lists of tree are changed in place, later elements of a list move one
+// index down.
+func Delete(tree interface{}, path string) (interface{}, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p) == 0 {
+		return nil, errors.New("bencode: delete: cannot delete the root value")
+	}
+
+	root, failed, err := deleteIn(tree, p, 0)
+	if err != nil {
+		return nil, &PathError{Op: "delete", Path: p, Failed: failed, Err: err}
+	}
+
+	return root, nil
+}
+
+// element of dict or list tree that segment names
+func child(tree interface{}, segment PathSegment) (interface{}, error) {
+	if segment.IsIndex {
+		list, ok := tree.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected list, found %s", describeValue(tree))
+		}
+		if segment.Index >= len(list) {
+			return nil, fmt.Errorf("%w, list has %d elements", ErrPathNotFound, len(list))
+		}
+		return list[segment.Index], nil
+	}
+
+	dict, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected dict, found %s", describeValue(tree))
+	}
+	value, ok := dict[segment.Key]
+	if !ok {
+		return nil, ErrPathNotFound
+	}
+	return value, nil
+}
+
+// Sets value at p[i:] inside tree, returns the new tree or the index of the
+// segment that failed
+func setIn(tree interface{}, p Path, i int, value interface{}) (interface{}, int, error) {
+	if i == len(p) {
+		return value, 0, nil
+	}
+
+	segment := p[i]
+
+	if segment.IsIndex {
+		list, ok := tree.([]interface{})
+		if !ok {
+			return nil, i, fmt.Errorf("expected list, found %s", describeValue(tree))
+		}
+
+		switch {
+		case segment.Index < len(list):
+			element, failed, err := setIn(list[segment.Index], p, i+1, value)
+			if err != nil {
+				return nil, failed, err
+			}
+			list[segment.Index] = element
+		case segment.Index == len(list) && i == len(p)-1:
+			list = append(list, value)
+		default:
+			return nil, i, fmt.Errorf("%w, list has %d elements", ErrPathNotFound, len(list))
+		}
+		return list, 0, nil
+	}
+
+	if tree == nil {
+		tree = map[string]interface{}{}
+	}
+
+	dict, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, i, fmt.Errorf("expected dict, found %s", describeValue(tree))
+	}
+
+	current, exists := dict[segment.Key]
+	if !exists && i < len(p)-1 && p[i+1].IsIndex {
+		return nil, i, ErrPathNotFound
+	}
+
+	element, failed, err := setIn(current, p, i+1, value)
+	if err != nil {
+		return nil, failed, err
+	}
+	dict[segment.Key] = element
+
+	return dict, 0, nil
+}
+
+// Deletes p[i:] from tree, returns the new tree or the index of the segment
+// that failed
+func deleteIn(tree interface{}, p Path, i int) (interface{}, int, error) {
+	segment := p[i]
+	last := i == len(p)-1
+
+	if segment.IsIndex {
+		list, ok := tree.([]interface{})
+		if !ok {
+			return nil, i, fmt.Errorf("expected list, found %s", describeValue(tree))
+		}
+		if segment.Index >= len(list) {
+			return nil, i, fmt.Errorf("%w, list has %d elements", ErrPathNotFound, len(list))
+		}
+
+		if last {
+			return append(list[:segment.Index], list[segment.Index+1:]...), 0, nil
+		}
+
+		element, failed, err := deleteIn(list[segment.Index], p, i+1)
+		if err != nil {
+			return nil, failed, err
+		}
+		list[segment.Index] = element
+		return list, 0, nil
+	}
+
+	dict, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, i, fmt.Errorf("expected dict, found %s", describeValue(tree))
+	}
+
+	current, exists := dict[segment.Key]
+	if !exists {
+		return nil, i, ErrPathNotFound
+	}
+
+	if last {
+		delete(dict, segment.Key)
+		return dict, 0, nil
+	}
+
+	element, failed, err := deleteIn(current, p, i+1)
+	if err != nil {
+		return nil, failed, err
+	}
+	dict[segment.Key] = element
+
+	return dict, 0, nil
+}
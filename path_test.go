@@ -0,0 +1,290 @@
+package decodebencode_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func pathTree(t *testing.T) interface{} {
+	t.Helper()
+
+	tree, err := decodebencode.DecodeBencode("d4:infod5:filesld4:pathl1:aeed4:pathl1:b1:ceee10:name.utf-84:bookee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestParsePath(t *testing.T) {
+	type TestCase struct {
+		input    string
+		expected decodebencode.Path
+	}
+
+	testCases := []TestCase{
+		{input: "", expected: decodebencode.Path{}},
+		{input: "info", expected: decodebencode.Path{{Key: "info"}}},
+		{
+			input: "info.files[2].path[0]",
+			expected: decodebencode.Path{
+				{Key: "info"}, {Key: "files"}, {Index: 2, IsIndex: true}, {Key: "path"}, {Index: 0, IsIndex: true},
+			},
+		},
+		{input: `info["name.utf-8"]`, expected: decodebencode.Path{{Key: "info"}, {Key: "name.utf-8"}}},
+		{input: `[""]`, expected: decodebencode.Path{{Key: ""}}},
+		{input: `["a]b"][1]`, expected: decodebencode.Path{{Key: "a]b"}, {Index: 1, IsIndex: true}}},
+		{input: "[0].a", expected: decodebencode.Path{{Index: 0, IsIndex: true}, {Key: "a"}}},
+		{input: "piece length", expected: decodebencode.Path{{Key: "piece length"}}},
+		{input: `files["\x00\xff\x1b"]`, expected: decodebencode.Path{{Key: "files"}, {Key: "\x00\xff\x1b"}}},
+	}
+
+	for _, tc := range testCases {
+		result, err := decodebencode.ParsePath(tc.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%q: Expected %v, got %v", tc.input, tc.expected, result)
+		}
+		if result.String() != tc.input {
+			t.Errorf("Expected %q, got %q", tc.input, result.String())
+		}
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	type TestCase struct {
+		input  string
+		offset int
+	}
+
+	testCases := []TestCase{
+		{input: ".info", offset: 0},
+		{input: "info.", offset: 5},
+		{input: "info..name", offset: 5},
+		{input: "info[", offset: 4},
+		{input: "info[x]", offset: 5},
+		{input: "info[-1]", offset: 5},
+		{input: "info[]", offset: 5},
+		{input: `info["name`, offset: 5},
+		{input: `info["name"`, offset: 11},
+		{input: "info]", offset: 4},
+		{input: "a[0]b", offset: 4},
+	}
+
+	for _, tc := range testCases {
+		_, err := decodebencode.ParsePath(tc.input)
+
+		var syntaxError *decodebencode.PathSyntaxError
+		if !errors.As(err, &syntaxError) {
+			t.Errorf("%q: Expected PathSyntaxError, got %v", tc.input, err)
+			continue
+		}
+		if syntaxError.Offset != tc.offset {
+			t.Errorf("%q: Expected offset %d, got %d (%v)", tc.input, tc.offset, syntaxError.Offset, err)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	type TestCase struct {
+		path     string
+		expected interface{}
+	}
+
+	testCases := []TestCase{
+		{path: "info.files[1].path[1]", expected: "c"},
+		{path: "info.files[0].path", expected: []interface{}{"a"}},
+		{path: `info["name.utf-8"]`, expected: "book"},
+	}
+
+	tree := pathTree(t)
+	for _, tc := range testCases {
+		result, err := decodebencode.Get(tree, tc.path)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("Expected %v, got %v", tc.expected, result)
+		}
+		if !decodebencode.Exists(tree, tc.path) {
+			t.Errorf("%q: Expected path to exist", tc.path)
+		}
+	}
+
+	root, err := decodebencode.Get(tree, "")
+	if err != nil || !reflect.DeepEqual(root, tree) {
+		t.Errorf("Expected the root, got %v, %v", root, err)
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	type TestCase struct {
+		path     string
+		expected string
+		notFound bool
+	}
+
+	testCases := []TestCase{
+		{
+			path:     "info.files[2].path[0]",
+			expected: "bencode: get `info.files[2].path[0]`: segment `[2]` of `info.files[2]`: not found, list has 2 elements",
+			notFound: true,
+		},
+		{
+			path:     "info.name",
+			expected: "bencode: get `info.name`: segment `name` of `info.name`: not found",
+			notFound: true,
+		},
+		{
+			path:     "info.files.path",
+			expected: "bencode: get `info.files.path`: segment `path` of `info.files.path`: expected dict, found list",
+		},
+		{
+			path:     `info["name.utf-8"][0]`,
+			expected: "bencode: get `info[\"name.utf-8\"][0]`: segment `[0]` of `info[\"name.utf-8\"][0]`: expected list, found string",
+		},
+	}
+
+	tree := pathTree(t)
+	for _, tc := range testCases {
+		_, err := decodebencode.Get(tree, tc.path)
+		if err == nil {
+			t.Errorf("%q: Expected an error", tc.path)
+			continue
+		}
+		if err.Error() != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, err.Error())
+		}
+		if errors.Is(err, decodebencode.ErrPathNotFound) != tc.notFound {
+			t.Errorf("%q: Expected errors.Is ErrPathNotFound to be %v", tc.path, tc.notFound)
+		}
+		if decodebencode.Exists(tree, tc.path) {
+			t.Errorf("%q: Expected path not to exist", tc.path)
+		}
+	}
+
+	if decodebencode.Exists(tree, "info..files") {
+		t.Errorf("Expected invalid path not to exist")
+	}
+}
+
+func TestSet(t *testing.T) {
+	type TestCase struct {
+		path     string
+		value    interface{}
+		expected string
+	}
+
+	testCases := []TestCase{
+		{path: "info.files[1].path[0]", value: "x", expected: "d4:infod5:filesld4:pathl1:aeed4:pathl1:x1:ceee10:name.utf-84:bookee"},
+		{path: "info.files[0].path[1]", value: "x", expected: "d4:infod5:filesld4:pathl1:a1:xeed4:pathl1:b1:ceee10:name.utf-84:bookee"},
+		{path: `info["name.utf-8"]`, value: 1, expected: "d4:infod5:filesld4:pathl1:aeed4:pathl1:b1:ceee10:name.utf-8i1eee"},
+		{path: "announce.tier.url", value: "u", expected: "d8:announced4:tierd3:url1:uee4:infod5:filesld4:pathl1:aeed4:pathl1:b1:ceee10:name.utf-84:bookee"},
+		{path: "", value: 5, expected: "i5e"},
+	}
+
+	for _, tc := range testCases {
+		result, err := decodebencode.Set(pathTree(t), tc.path, tc.value)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.path, err)
+			continue
+		}
+		encoded, err := decodebencode.Marshal(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(encoded) != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, encoded)
+		}
+	}
+
+	result, err := decodebencode.Set(nil, "a.b", 1)
+	if err != nil || !reflect.DeepEqual(result, map[string]interface{}{"a": map[string]interface{}{"b": 1}}) {
+		t.Errorf("Expected new dicts, got %v, %v", result, err)
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	type TestCase struct {
+		path     string
+		expected string
+	}
+
+	testCases := []TestCase{
+		{
+			path:     "info.files[3]",
+			expected: "bencode: set `info.files[3]`: segment `[3]` of `info.files[3]`: not found, list has 2 elements",
+		},
+		{
+			path:     "info.files[2].path",
+			expected: "bencode: set `info.files[2].path`: segment `[2]` of `info.files[2]`: not found, list has 2 elements",
+		},
+		{
+			path:     "info.trackers[0]",
+			expected: "bencode: set `info.trackers[0]`: segment `trackers` of `info.trackers`: not found",
+		},
+		{
+			path:     `info["name.utf-8"].first`,
+			expected: "bencode: set `info[\"name.utf-8\"].first`: segment `first` of `info[\"name.utf-8\"].first`: expected dict, found string",
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := decodebencode.Set(pathTree(t), tc.path, "x")
+		if err == nil {
+			t.Errorf("%q: Expected an error", tc.path)
+			continue
+		}
+		if err.Error() != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, err.Error())
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type TestCase struct {
+		path     string
+		expected string
+	}
+
+	testCases := []TestCase{
+		{path: "info.files[0]", expected: "d4:infod5:filesld4:pathl1:b1:ceee10:name.utf-84:bookee"},
+		{path: "info.files[1].path[0]", expected: "d4:infod5:filesld4:pathl1:aeed4:pathl1:ceee10:name.utf-84:bookee"},
+		{path: `info["name.utf-8"]`, expected: "d4:infod5:filesld4:pathl1:aeed4:pathl1:b1:ceeeee"},
+		{path: "info", expected: "de"},
+	}
+
+	for _, tc := range testCases {
+		result, err := decodebencode.Delete(pathTree(t), tc.path)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.path, err)
+			continue
+		}
+		encoded, err := decodebencode.Marshal(result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(encoded) != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, encoded)
+		}
+	}
+
+	_, err := decodebencode.Delete(pathTree(t), "info.files[1].name")
+	expected := "bencode: delete `info.files[1].name`: segment `name` of `info.files[1].name`: not found"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected %q, got %v", expected, err)
+	}
+	if !errors.Is(err, decodebencode.ErrPathNotFound) {
+		t.Errorf("Expected errors.Is ErrPathNotFound")
+	}
+
+	if _, err := decodebencode.Delete(pathTree(t), ""); err == nil {
+		t.Errorf("Expected an error deleting the root")
+	}
+}
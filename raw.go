@@ -0,0 +1,126 @@
+package decodebencode
+
+import "errors"
+
+// Bencode value kept as it is. Marshal copies it to the output verbatim once
+// it is a single value DecodeBencode accepts, non-canonical integers and
+// unsorted keys included, so a torrent can be written again with its info dict
+// untouched and its infohash intact. Unmarshal stores the input bytes of the
+// value in it without decoding them.
+type RawValue []byte
+
+// Returns r itself, Marshal checks it is a single value
+func (r RawValue) MarshalBencode() ([]byte, error) {
+	if len(r) == 0 {
+		return nil, errors.New("empty RawValue")
+	}
+	return r, nil
+}
+
+// Stores a copy of data in r
+func (r *RawValue) UnmarshalBencode(data []byte) error {
+	if r == nil {
+		return errors.New("bencode: UnmarshalBencode on nil *RawValue")
+	}
+	*r = append((*r)[:0], data...)
+	return nil
+}
+
+// Finds where values of the decoded tree are in the input, so that
+// Unmarshalers get their bytes exactly as they were. Lists and dicts are
+// split into their elements once, when something inside them is looked up.
+type rawIndex struct {
+	data []byte
+	// elements of the lists and dicts split so far, by their offset in data
+	containers map[int]*rawElements
+}
+
+type rawElements struct {
+	items [][]byte
+	// the last value of every key, as DecodeBencode keeps it
+	keys map[string][]byte
+}
+
+// Input of the value at path, false when it cannot be found
+func (x *rawIndex) lookup(path Path) ([]byte, bool) {
+	if x == nil {
+		return nil, false
+	}
+
+	raw := x.data
+	for _, segment := range path {
+		elements, ok := x.elements(raw)
+		if !ok {
+			return nil, false
+		}
+
+		if segment.IsIndex {
+			if segment.Index < 0 || segment.Index >= len(elements.items) {
+				return nil, false
+			}
+			raw = elements.items[segment.Index]
+			continue
+		}
+
+		if raw, ok = elements.keys[segment.Key]; !ok {
+			return nil, false
+		}
+	}
+
+	return raw, true
+}
+
+// splits raw, a subslice of x.data, into its elements
+func (x *rawIndex) elements(raw []byte) (*rawElements, bool) {
+	offset := cap(x.data) - cap(raw)
+	if elements, ok := x.containers[offset]; ok {
+		return elements, true
+	}
+
+	t := NewTokenizer(raw)
+	start, err := t.Next()
+	if err != nil || (start.Kind != TokenListStart && start.Kind != TokenDictStart) {
+		return nil, false
+	}
+
+	elements := &rawElements{}
+	if start.Kind == TokenDictStart {
+		elements.keys = map[string][]byte{}
+	}
+
+	for {
+		tok, err := t.Next()
+		if err != nil {
+			return nil, false
+		}
+		if tok.Kind == TokenEnd {
+			break
+		}
+
+		if start.Kind == TokenListStart {
+			item, err := t.SkipValue(tok)
+			if err != nil {
+				return nil, false
+			}
+			elements.items = append(elements.items, item)
+			continue
+		}
+
+		value, err := t.Next()
+		if err != nil {
+			return nil, false
+		}
+		item, err := t.SkipValue(value)
+		if err != nil {
+			return nil, false
+		}
+		elements.keys[string(tok.Value)] = item
+	}
+
+	if x.containers == nil {
+		x.containers = map[int]*rawElements{}
+	}
+	x.containers[offset] = elements
+
+	return elements, true
+}
@@ -0,0 +1,156 @@
+package decodebencode_test
+
+import (
+	"crypto/sha1"
+	"errors"
+	"reflect"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+// info dict with unsorted keys and a binary value, re-encoding it would change
+// its hash
+const rawInfo = "d6:pieces4:\x00\xff\x00e4:name5:a.iso12:piece lengthi16384ee"
+
+type rawTorrent struct {
+	Announce string                   `bencode:"announce"`
+	Info     decodebencode.RawValue   `bencode:"info"`
+	Nodes    []decodebencode.RawValue `bencode:"nodes,omitempty"`
+}
+
+func TestMarshalRawValue(t *testing.T) {
+	type TestCase struct {
+		name     string
+		input    any
+		expected string
+	}
+
+	testCases := []TestCase{
+		{name: "raw value", input: decodebencode.RawValue("li1ee"), expected: "li1ee"},
+		{
+			name:     "kept as is in struct",
+			input:    rawTorrent{Announce: "udp://t", Info: decodebencode.RawValue(rawInfo)},
+			expected: "d8:announce7:udp://t4:info" + rawInfo + "e",
+		},
+		{
+			name:     "unsorted keys are kept",
+			input:    []any{decodebencode.RawValue("d1:bi1e1:ai2ee"), 1},
+			expected: "ld1:bi1e1:ai2eei1ee",
+		},
+		{
+			name:     "non-canonical integers are kept",
+			input:    []any{decodebencode.RawValue("li007ei+7ei-0e03:abce")},
+			expected: "lli007ei+7ei-0e03:abcee",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := decodebencode.Marshal(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if string(output) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+
+			if size := decodebencode.EncodedLen(tc.input); size != int64(len(tc.expected)) {
+				t.Errorf("Expected EncodedLen %d, got %d", len(tc.expected), size)
+			}
+		})
+	}
+
+	dict, err := decodebencode.EncodeBencodeDict(map[string]any{"info": decodebencode.RawValue(rawInfo), "a": 1})
+	if err != nil || dict != "d1:ai1e4:info"+rawInfo+"e" {
+		t.Errorf("Unexpected %q, %v", dict, err)
+	}
+}
+
+func TestMarshalInvalidRawValue(t *testing.T) {
+	testCases := map[string]decodebencode.RawValue{
+		"nil":              nil,
+		"two values":       decodebencode.RawValue("i1ei2e"),
+		"unclosed list":    decodebencode.RawValue("li1e"),
+		"truncated string": decodebencode.RawValue("5:abc"),
+		"not an integer":   decodebencode.RawValue("i7xe"),
+		"integer overflow": decodebencode.RawValue("i9223372036854775808e"),
+		"integer dict key": decodebencode.RawValue("di1ei2ee"),
+	}
+
+	for name, raw := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodebencode.Marshal(map[string]any{"info": raw})
+
+			var marshalerError *decodebencode.MarshalerError
+			if !errors.As(err, &marshalerError) || marshalerError.Path.String() != "info" {
+				t.Errorf("Expected *MarshalerError at `info`, got %v", err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalRawValue(t *testing.T) {
+	input := "d8:announce7:udp://t4:info" + rawInfo + "5:nodesl4:spamd1:bi1e1:ai2eeee"
+
+	var torrent rawTorrent
+	if err := decodebencode.Unmarshal([]byte(input), &torrent); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := rawTorrent{
+		Announce: "udp://t",
+		Info:     decodebencode.RawValue(rawInfo),
+		Nodes:    []decodebencode.RawValue{decodebencode.RawValue("4:spam"), decodebencode.RawValue("d1:bi1e1:ai2ee")},
+	}
+	if !reflect.DeepEqual(torrent, expected) {
+		t.Errorf("Expected %q, got %q", expected, torrent)
+	}
+
+	infohash, err := decodebencode.HashOf(torrent.Info, sha1.New)
+	expectedHash := sha1.Sum([]byte(rawInfo))
+	if err != nil || string(infohash) != string(expectedHash[:]) {
+		t.Errorf("Expected infohash %x, got %x, %v", expectedHash, infohash, err)
+	}
+
+	torrent.Announce = "udp://other"
+	output, err := decodebencode.Marshal(torrent)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(output) != "d8:announce11:udp://other4:info"+rawInfo+"5:nodesl4:spamd1:bi1e1:ai2eeee" {
+		t.Errorf("Unexpected output %q", output)
+	}
+}
+
+func TestRawValueRoundTripNonCanonical(t *testing.T) {
+	input := "d8:announce1:x4:infod6:lengthi+7e4:name03:abcee"
+
+	var torrent rawTorrent
+	if err := decodebencode.Unmarshal([]byte(input), &torrent); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	output, err := decodebencode.Marshal(torrent)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(output) != input {
+		t.Errorf("Expected %q, got %q", input, output)
+	}
+}
+
+func TestUnmarshalRawValueDuplicateKeys(t *testing.T) {
+	var output struct {
+		Info decodebencode.RawValue `bencode:"info"`
+	}
+
+	if err := decodebencode.Unmarshal([]byte("d4:infoi1e4:infoli2eee"), &output); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if string(output.Info) != "li2ee" {
+		t.Errorf("Expected the last value, got %q", output.Info)
+	}
+}
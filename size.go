@@ -0,0 +1,45 @@
+package decodebencode
+
+import "reflect"
+
+// Size in bytes of what Marshal(v) returns, without producing the output:
+// integers and strings are only measured. Values implementing Marshaler are
+// still asked to encode themselves. Returns -1 when Marshal(v) would fail.
+func EncodedLen(v any) int64 {
+	e := encodeState{sizeOnly: true}
+
+	if err := e.marshal(reflect.ValueOf(v)); err != nil {
+		return -1
+	}
+
+	return e.size
+}
+
+// number of decimal digits of u
+func digits(u uint64) int64 {
+	n := int64(1)
+	for u >= 10 {
+		u /= 10
+		n++
+	}
+	return n
+}
+
+// length of `i<u>e`
+func uintLen(u uint64) int64 {
+	return digits(u) + 2
+}
+
+// length of `i<i>e`
+func intLen(i int64) int64 {
+	if i < 0 {
+		// -i overflows for math.MinInt64, its uint64 conversion doesn't
+		return digits(uint64(-(i+1))+1) + 3
+	}
+	return digits(uint64(i)) + 2
+}
+
+// length of a byte string of n bytes, `<n>:` included
+func stringLen(n int) int64 {
+	return digits(uint64(n)) + 1 + int64(n)
+}
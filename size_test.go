@@ -0,0 +1,109 @@
+package decodebencode_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func TestEncodedLen(t *testing.T) {
+	one := int8(1)
+	hash := [20]byte{}
+
+	inputs := []any{
+		0,
+		9,
+		10,
+		-1,
+		-10,
+		math.MaxInt64,
+		math.MinInt64,
+		uint64(math.MaxUint64),
+		"",
+		"hi!",
+		"ゴゴゴゴ",
+		strings.Repeat("x", 12345),
+		[]byte{0, 1, 2},
+		hash,
+		&hash,
+		[]any{1, "x", []int{}, map[string]any{}},
+		map[[2]byte]int{{1, 2}: 3},
+		map[int]string{10: "ten", -1: "minus"},
+		marshalInfo{Name: "a", Files: []marshalFile{{Length: 7, Path: []string{"a"}}}, Private: &one},
+		compactPeers{{1, 2, 3, 4, 5, 6}},
+		[]bitfield{{bits: 0xff}},
+		torrentFile{Name: "a", Length: 1},
+	}
+
+	for _, input := range inputs {
+		encoded, err := decodebencode.Marshal(input)
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", input, err)
+		}
+
+		if size := decodebencode.EncodedLen(input); size != int64(len(encoded)) {
+			t.Errorf("Expected %d for %q, got %d", len(encoded), encoded, size)
+		}
+	}
+}
+
+func TestEncodedLenUnsupported(t *testing.T) {
+	inputs := []any{nil, 1.5, []any{1, true}, map[string]any{"x": brokenMarshaler("")}}
+
+	for _, input := range inputs {
+		if size := decodebencode.EncodedLen(input); size != -1 {
+			t.Errorf("Expected -1 for %v, got %d", input, size)
+		}
+	}
+}
+
+func TestEncodeOptionsEncodedLen(t *testing.T) {
+	opts := decodebencode.EncodeOptions{
+		Nil:   decodebencode.NilEmptyString,
+		Bool:  decodebencode.BoolInteger,
+		Float: decodebencode.FloatString,
+	}
+
+	inputs := []any{
+		true,
+		nil,
+		0.1,
+		float32(-2.5),
+		1e21,
+		[]any{false, nil, 3.25},
+		map[string]any{"private": true, "ratio": 1.5, "comment": nil},
+	}
+
+	for _, input := range inputs {
+		encoded, err := opts.Marshal(input)
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", input, err)
+		}
+
+		if size := opts.EncodedLen(input); size != int64(len(encoded)) {
+			t.Errorf("Expected %d for %q, got %d", len(encoded), encoded, size)
+		}
+	}
+
+	if size := opts.EncodedLen(math.NaN()); size != -1 {
+		t.Errorf("Expected -1 for NaN, got %d", size)
+	}
+	if size := decodebencode.EncodedLen(true); size != -1 {
+		t.Errorf("Expected -1 for bool without options, got %d", size)
+	}
+}
+
+func TestEncodedLenDoesNotAllocate(t *testing.T) {
+	input := []any{strings.Repeat("x", 1<<16), 1}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		decodebencode.EncodedLen(input)
+	})
+
+	// reflect.ValueOf(input) is all that may allocate
+	if allocs > 1 {
+		t.Errorf("Expected no allocations for the output, got %v", allocs)
+	}
+}
@@ -0,0 +1,77 @@
+package decodebencode
+
+import (
+	"encoding"
+	"reflect"
+)
+
+// Types implementing encoding.BinaryMarshaler or encoding.TextMarshaler are
+// encoded as byte strings of what MarshalBinary or MarshalText return and
+// decoded back with UnmarshalBinary or UnmarshalText. Binary is tried first,
+// it is what bencode keeps best. Marshaler and registered converters take
+// precedence over both.
+
+// Returned when UnmarshalBencode, UnmarshalBinary or UnmarshalText fails
+type UnmarshalerError struct {
+	Type reflect.Type
+	Err  error
+	Path Path
+	// method that failed
+	sourceFunc string
+}
+
+func (e *UnmarshalerError) Error() string {
+	return "bencode: error calling " + e.sourceFunc + " for type " + e.Type.String() + atPath(e.Path) + ": " + e.Err.Error()
+}
+
+func (e *UnmarshalerError) Unwrap() error {
+	return e.Err
+}
+
+func (e *encodeState) marshalBinary(v reflect.Value, marshaler encoding.BinaryMarshaler) error {
+	b, err := marshaler.MarshalBinary()
+	if err != nil {
+		return &MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalBinary"}
+	}
+
+	e.writeBytes(b)
+	return nil
+}
+
+func (e *encodeState) marshalText(v reflect.Value, marshaler encoding.TextMarshaler) error {
+	text, err := marshaler.MarshalText()
+	if err != nil {
+		return &MarshalerError{Type: v.Type(), Err: err, sourceFunc: "MarshalText"}
+	}
+
+	e.writeBytes(text)
+	return nil
+}
+
+// Decodes tree with UnmarshalBinary or UnmarshalText when v implements one of
+// them, handled tells whether it did
+func unmarshalStd(tree any, v reflect.Value) (handled bool, err error) {
+	binary, isBinary := implementation[encoding.BinaryUnmarshaler](v)
+	text, isText := implementation[encoding.TextUnmarshaler](v)
+
+	if !isBinary && !isText {
+		return false, nil
+	}
+
+	s, ok := tree.(string)
+	if !ok {
+		return true, &UnmarshalTypeError{Value: describeValue(tree), Type: v.Type()}
+	}
+
+	if isBinary {
+		if err := binary.UnmarshalBinary([]byte(s)); err != nil {
+			return true, &UnmarshalerError{Type: v.Type(), Err: err, sourceFunc: "UnmarshalBinary"}
+		}
+		return true, nil
+	}
+
+	if err := text.UnmarshalText([]byte(s)); err != nil {
+		return true, &UnmarshalerError{Type: v.Type(), Err: err, sourceFunc: "UnmarshalText"}
+	}
+	return true, nil
+}
@@ -0,0 +1,154 @@
+package decodebencode_test
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+// implements only encoding.TextMarshaler and encoding.TextUnmarshaler
+type version struct {
+	Major, Minor int
+}
+
+func (v version) MarshalText() ([]byte, error) {
+	if v.Major < 0 {
+		return nil, errors.New("negative version")
+	}
+	return fmt.Appendf(nil, "%d.%d", v.Major, v.Minor), nil
+}
+
+func (v *version) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d.%d", &v.Major, &v.Minor)
+	return err
+}
+
+// implements both binary and text interfaces, binary must win
+type flags uint16
+
+func (f flags) MarshalBinary() ([]byte, error) {
+	return []byte{byte(f >> 8), byte(f)}, nil
+}
+
+func (f *flags) UnmarshalBinary(b []byte) error {
+	if len(b) != 2 {
+		return fmt.Errorf("flags of %d bytes", len(b))
+	}
+	*f = flags(b[0])<<8 | flags(b[1])
+	return nil
+}
+
+func (f flags) MarshalText() ([]byte, error) {
+	return fmt.Appendf(nil, "%016b", uint16(f)), nil
+}
+
+func (f *flags) UnmarshalText([]byte) error {
+	return errors.New("text form is for humans only")
+}
+
+type client struct {
+	Version version  `bencode:"v"`
+	Flags   flags    `bencode:"flags"`
+	Serial  *big.Int `bencode:"serial"`
+}
+
+func TestMarshalStdInterfaces(t *testing.T) {
+	type TestCase struct {
+		name     string
+		input    any
+		expected string
+	}
+
+	testCases := []TestCase{
+		{name: "text", input: version{1, 2}, expected: "3:1.2"},
+		{name: "binary wins over text", input: flags(0x0102), expected: "2:\x01\x02"},
+		{name: "big int", input: big.NewInt(-12345678901), expected: "12:-12345678901"},
+		{name: "list of versions", input: []version{{0, 1}, {2, 0}}, expected: "l3:0.13:2.0e"},
+		{
+			name:     "struct",
+			input:    client{Version: version{1, 10}, Flags: 3, Serial: big.NewInt(7)},
+			expected: "d5:flags2:\x00\x036:serial1:71:v4:1.10e",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := decodebencode.Marshal(tc.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if string(output) != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestMarshalStdInterfacesError(t *testing.T) {
+	_, err := decodebencode.Marshal(map[string]any{"v": version{-1, 0}})
+
+	var marshalerError *decodebencode.MarshalerError
+	if !errors.As(err, &marshalerError) {
+		t.Fatalf("Expected *MarshalerError, got %v", err)
+	}
+
+	expected := "bencode: error calling MarshalText for type decodebencode_test.version at `v`: negative version"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestUnmarshalStdInterfaces(t *testing.T) {
+	input := client{Version: version{3, 14}, Flags: 0xabcd, Serial: new(big.Int).Lsh(big.NewInt(1), 100)}
+
+	data, err := decodebencode.Marshal(input)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var output client
+	if err := decodebencode.Unmarshal(data, &output); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(output, input) {
+		t.Errorf("Expected %+v, got %+v", input, output)
+	}
+}
+
+func TestUnmarshalStdInterfacesErrors(t *testing.T) {
+	type TestCase struct {
+		name     string
+		input    string
+		expected string
+	}
+
+	testCases := []TestCase{
+		{
+			name:     "binary error",
+			input:    "d5:flags3:abce",
+			expected: "bencode: error calling UnmarshalBinary for type decodebencode_test.flags at `flags`: flags of 3 bytes",
+		},
+		{
+			name:     "integer instead of string",
+			input:    "d1:vi1ee",
+			expected: "bencode: cannot unmarshal integer 1 into Go value of type decodebencode_test.version at `v`",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var output client
+			err := decodebencode.Unmarshal([]byte(tc.input), &output)
+
+			if err == nil || err.Error() != tc.expected {
+				t.Errorf("Expected %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
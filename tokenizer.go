@@ -0,0 +1,181 @@
+package decodebencode
+
+import (
+	"fmt"
+	"io"
+)
+
+type TokenKind int
+
+const (
+	TokenInt TokenKind = iota + 1
+	TokenString
+	TokenListStart
+	TokenDictStart
+	TokenEnd
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenInt:
+		return "integer"
+	case TokenString:
+		return "string"
+	case TokenListStart:
+		return "list start"
+	case TokenDictStart:
+		return "dict start"
+	case TokenEnd:
+		return "end"
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(k))
+}
+
+// Single bencode token. Raw is the token exactly as it is in the input, Value
+// holds the digits of an integer (sign included) or the bytes of a string.
+// Both point into the input, nothing is copied.
+type Token struct {
+	Kind   TokenKind
+	Offset int
+	Raw    []byte
+	Value  []byte
+}
+
+// Value of an integer token
+func (t Token) Int() (int, error) {
+	if t.Kind != TokenInt {
+		return 0, fmt.Errorf("bencode: %v token on index %d is not an integer", t.Kind, t.Offset)
+	}
+
+	num, ok := parseInteger(t.Value)
+	if !ok {
+		return 0, fmt.Errorf("cannot convert %q to int on index %d", t.Value, t.Offset)
+	}
+
+	return num, nil
+}
+
+// Tokenizer splits bencode into tokens without building any values and
+// without allocating. It accepts everything DecodeBencode does, integers of
+// any size included, and checks that every TokenEnd closes a list or a dict.
+// It does not check what dict keys are, that is up to the caller.
+type Tokenizer struct {
+	data  []byte
+	pos   int
+	depth int
+}
+
+func NewTokenizer(data []byte) *Tokenizer {
+	return &Tokenizer{data: data}
+}
+
+// Offset of the next token
+func (t *Tokenizer) Offset() int {
+	return t.pos
+}
+
+// Number of lists and dicts opened and not closed yet
+func (t *Tokenizer) Depth() int {
+	return t.depth
+}
+
+// Returns next token, io.EOF once the input is over and io.ErrUnexpectedEOF
+// when it is over inside a list or dict.
+func (t *Tokenizer) Next() (Token, error) {
+	if t.pos >= len(t.data) {
+		if t.depth > 0 {
+			return Token{}, fmt.Errorf("bencode: %d lists or dicts are not closed: %w", t.depth, io.ErrUnexpectedEOF)
+		}
+		return Token{}, io.EOF
+	}
+
+	start := t.pos
+	c := t.data[start]
+
+	switch {
+	case c == INT_CONTROL_SYMBOL:
+		end := indexByte(t.data, start+1, CLOSE_CONTROL_SYMBOL)
+		if end < 0 {
+			return Token{}, fmt.Errorf("cannot find closing symbol %v for integer, starting from: %d", string(CLOSE_CONTROL_SYMBOL), start+1)
+		}
+
+		digits := t.data[start+1 : end]
+		if !isIntegerSyntax(digits) {
+			return Token{}, fmt.Errorf("cannot convert %q to int on index %d", digits, start+1)
+		}
+
+		t.pos = end + 1
+		return Token{Kind: TokenInt, Offset: start, Raw: t.data[start:t.pos], Value: digits}, nil
+
+	case c == LIST_CONTROL_SYMBOL || c == DICT_CONTROL_SYMBOL:
+		t.pos++
+		t.depth++
+		kind := TokenListStart
+		if c == DICT_CONTROL_SYMBOL {
+			kind = TokenDictStart
+		}
+		return Token{Kind: kind, Offset: start, Raw: t.data[start:t.pos]}, nil
+
+	case c == CLOSE_CONTROL_SYMBOL:
+		if t.depth == 0 {
+			return Token{}, fmt.Errorf("unexpected closing symbol %v on index %d, there is no open list or dictionary", string(CLOSE_CONTROL_SYMBOL), start)
+		}
+		t.pos++
+		t.depth--
+		return Token{Kind: TokenEnd, Offset: start, Raw: t.data[start:t.pos]}, nil
+
+	case c >= '0' && c <= '9':
+		colon := indexByte(t.data, start, STR_CONTROL_SYMBOL)
+		if colon < 0 {
+			return Token{}, fmt.Errorf("cannot find closing symbol %v for string, starting from: %d", string(STR_CONTROL_SYMBOL), start)
+		}
+
+		length, ok := parseInteger(t.data[start:colon])
+		if !ok || length > len(t.data)-colon-1 {
+			return Token{}, fmt.Errorf("wrong string encoding on index %d: length %q is wrong or greater than remaining %d bytes", start, t.data[start:colon], len(t.data)-colon-1)
+		}
+
+		t.pos = colon + 1 + length
+		return Token{Kind: TokenString, Offset: start, Raw: t.data[start:t.pos], Value: t.data[colon+1 : t.pos]}, nil
+	}
+
+	return Token{}, fmt.Errorf("parsing error, expected digit, got %q on index %d", c, start)
+}
+
+// Skips the rest of the value tok starts, tok being the token Next returned
+// last. Returns the whole value as it is in the input.
+func (t *Tokenizer) SkipValue(tok Token) ([]byte, error) {
+	if tok.Kind != TokenListStart && tok.Kind != TokenDictStart {
+		if tok.Kind == TokenEnd {
+			return nil, fmt.Errorf("bencode: expected a value on index %d, got %v", tok.Offset, tok.Kind)
+		}
+		return tok.Raw, nil
+	}
+
+	for depth := t.depth - 1; t.depth > depth; {
+		if _, err := t.Next(); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.data[tok.Offset:t.pos], nil
+}
+
+// optional sign followed by at least one digit
+func isIntegerSyntax(digits []byte) bool {
+	if len(digits) > 0 && (digits[0] == '-' || digits[0] == '+') {
+		digits = digits[1:]
+	}
+
+	if len(digits) == 0 {
+		return false
+	}
+
+	for _, d := range digits {
+		if d < '0' || d > '9' {
+			return false
+		}
+	}
+
+	return true
+}
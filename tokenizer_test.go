@@ -0,0 +1,169 @@
+package decodebencode_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func TestTokenizer(t *testing.T) {
+	type token struct {
+		Kind   decodebencode.TokenKind
+		Offset int
+		Raw    string
+		Value  string
+	}
+
+	type TestCase struct {
+		name     string
+		input    string
+		expected []token
+	}
+
+	testCases := []TestCase{
+		{
+			name:     "integer",
+			input:    "i-42e",
+			expected: []token{{decodebencode.TokenInt, 0, "i-42e", "-42"}},
+		},
+		{
+			name:     "binary string",
+			input:    "3:\x00\xffe",
+			expected: []token{{decodebencode.TokenString, 0, "3:\x00\xffe", "\x00\xffe"}},
+		},
+		{
+			name:  "dict with list",
+			input: "d1:ali1eee",
+			expected: []token{
+				{decodebencode.TokenDictStart, 0, "d", ""},
+				{decodebencode.TokenString, 1, "1:a", "a"},
+				{decodebencode.TokenListStart, 4, "l", ""},
+				{decodebencode.TokenInt, 5, "i1e", "1"},
+				{decodebencode.TokenEnd, 8, "e", ""},
+				{decodebencode.TokenEnd, 9, "e", ""},
+			},
+		},
+		{
+			name:     "integer bigger than int64",
+			input:    "i123456789012345678901234567890e",
+			expected: []token{{decodebencode.TokenInt, 0, "i123456789012345678901234567890e", "123456789012345678901234567890"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := decodebencode.NewTokenizer([]byte(tc.input))
+			var output []token
+
+			for {
+				tok, err := tokens.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				output = append(output, token{tok.Kind, tok.Offset, string(tok.Raw), string(tok.Value)})
+			}
+
+			if !reflect.DeepEqual(output, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestTokenizerErrors(t *testing.T) {
+	type TestCase struct {
+		name       string
+		input      string
+		unexpected bool
+	}
+
+	testCases := []TestCase{
+		{name: "unclosed list", input: "li1e", unexpected: true},
+		{name: "unmatched end", input: "i1ee"},
+		{name: "empty integer", input: "ie"},
+		{name: "integer without end", input: "i42"},
+		{name: "string too short", input: "4:abc"},
+		{name: "unknown symbol", input: "x"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens := decodebencode.NewTokenizer([]byte(tc.input))
+
+			var err error
+			for err == nil {
+				_, err = tokens.Next()
+			}
+
+			if err == io.EOF {
+				t.Fatalf("Expected an error, got io.EOF")
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) != tc.unexpected {
+				t.Errorf("Expected io.ErrUnexpectedEOF %v, got %v", tc.unexpected, err)
+			}
+		})
+	}
+}
+
+func TestTokenInt(t *testing.T) {
+	tok, err := decodebencode.NewTokenizer([]byte("i-17e")).Next()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if num, err := tok.Int(); err != nil || num != -17 {
+		t.Errorf("Expected -17, got %v, %v", num, err)
+	}
+
+	tok, _ = decodebencode.NewTokenizer([]byte("1:a")).Next()
+	if _, err := tok.Int(); err == nil {
+		t.Errorf("Expected an error for a string token")
+	}
+}
+
+func TestTokenizerSkipValue(t *testing.T) {
+	input := "d1:ald1:xi1eee1:bi2e1:c3:\x00e\xffe"
+	tokens := decodebencode.NewTokenizer([]byte(input))
+
+	var skipped []string
+	tok, _ := tokens.Next()
+	for {
+		key, err := tokens.Next()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if key.Kind == decodebencode.TokenEnd {
+			break
+		}
+
+		value, _ := tokens.Next()
+		raw, err := tokens.SkipValue(value)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		skipped = append(skipped, string(raw))
+	}
+
+	expected := []string{"ld1:xi1eee", "i2e", "3:\x00e\xff"}
+	if !reflect.DeepEqual(skipped, expected) {
+		t.Errorf("Expected %q, got %q", expected, skipped)
+	}
+	if tok.Kind != decodebencode.TokenDictStart || tokens.Depth() != 0 {
+		t.Errorf("Expected the dict to be closed, depth is %d", tokens.Depth())
+	}
+
+	if _, err := decodebencode.NewTokenizer([]byte("li1e")).SkipValue(decodebencode.Token{Kind: decodebencode.TokenEnd}); err == nil {
+		t.Errorf("Expected an error for skipping an end token")
+	}
+	unclosed := decodebencode.NewTokenizer([]byte("li1e"))
+	start, _ := unclosed.Next()
+	if _, err := unclosed.SkipValue(start); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
@@ -0,0 +1,307 @@
+package decodebencode
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Returned by Unmarshal when v is not a non-nil pointer
+type InvalidUnmarshalError struct {
+	Type reflect.Type
+}
+
+func (e *InvalidUnmarshalError) Error() string {
+	if e.Type == nil {
+		return "bencode: Unmarshal(nil)"
+	}
+	return "bencode: Unmarshal(non-pointer or nil " + e.Type.String() + ")"
+}
+
+// Returned by Unmarshal when a decoded value doesn't fit the Go value it
+// should be stored in. Value describes the bencode value: "integer 300",
+// "string", "list" or "dict".
+type UnmarshalTypeError struct {
+	Value string
+	Type  reflect.Type
+	Path  Path
+}
+
+func (e *UnmarshalTypeError) Error() string {
+	return "bencode: cannot unmarshal " + e.Value + " into Go value of type " + e.Type.String() + atPath(e.Path)
+}
+
+// Returned by Unmarshal when a dict has no key for a `bencode:",required"` field
+type RequiredKeyError struct {
+	Key  string
+	Type reflect.Type
+	Path Path
+}
+
+func (e *RequiredKeyError) Error() string {
+	return fmt.Sprintf("bencode: missing required key %q for %s%s", e.Key, e.Type, atPath(e.Path))
+}
+
+// Implemented by types that decode themselves. UnmarshalBencode gets exactly
+// one bencode value and must copy it if it keeps it after returning.
+type Unmarshaler interface {
+	UnmarshalBencode([]byte) error
+}
+
+// Unmarshal decodes data with DecodeBencode and stores the result in the
+// value v points to, mirroring what Marshal does. Integers go to any integer
+// kind as long as they fit, strings go to strings, byte slices and byte arrays
+// of the same length, lists go to slices and arrays, dicts go to structs and
+// maps with the key types Marshal accepts. Types with a registered converter
+// are decoded by it, strings go to types implementing
+// encoding.BinaryUnmarshaler or encoding.TextUnmarshaler. Pointers are
+// allocated as needed, empty interfaces get the decoded value as is. Dict keys
+// are matched to field keys exactly, keys without a matching struct field are
+// ignored.
+//
+// Values implementing Unmarshaler decode themselves, they get their part of
+// data as is. RawValue keeps that part without decoding it.
+func Unmarshal(data []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+
+	if unmarshaler, ok := v.(Unmarshaler); ok {
+		if err := unmarshaler.UnmarshalBencode(data); err != nil {
+			return &UnmarshalerError{Type: rv.Type(), Err: err, sourceFunc: "UnmarshalBencode"}
+		}
+		return nil
+	}
+
+	tree, err := DecodeBencode(string(data))
+	if err != nil {
+		return err
+	}
+
+	if tree == nil {
+		return errors.New("bencode: cannot unmarshal empty input")
+	}
+
+	s := unmarshalState{raw: &rawIndex{data: data}}
+	return s.value(tree, rv.Elem())
+}
+
+func describeValue(tree any) string {
+	switch t := tree.(type) {
+	case int:
+		return fmt.Sprintf("integer %d", t)
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "dict"
+	}
+	return fmt.Sprintf("%T", tree)
+}
+
+// what Unmarshal needs while walking the decoded tree
+type unmarshalState struct {
+	// input the tree was decoded from, nil when it is not at hand
+	raw *rawIndex
+	// path of the value being decoded
+	path Path
+}
+
+// stores tree in v when the input tree came from is not at hand
+func unmarshalValue(tree any, v reflect.Value) error {
+	s := unmarshalState{}
+	return s.value(tree, v)
+}
+
+// decodes tree inside the value being decoded to v, the path of the error is
+// prepended with segment
+func (s *unmarshalState) child(tree any, v reflect.Value, segment PathSegment) error {
+	s.path = append(s.path, segment)
+	err := s.value(tree, v)
+	s.path = s.path[:len(s.path)-1]
+
+	if err != nil {
+		return prependPath(err, segment)
+	}
+	return nil
+}
+
+func (s *unmarshalState) value(tree any, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return s.value(tree, v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(tree))
+			return nil
+		}
+	}
+
+	if unmarshaler, ok := implementation[Unmarshaler](v); ok {
+		return s.unmarshaler(tree, v, unmarshaler)
+	}
+
+	if c, ok := converterFor(v.Type()); ok {
+		return unmarshalConverted(tree, v, c)
+	}
+
+	if handled, err := unmarshalStd(tree, v); handled {
+		return err
+	}
+
+	typeError := &UnmarshalTypeError{Value: describeValue(tree), Type: v.Type()}
+
+	switch t := tree.(type) {
+	case int:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(int64(t)) {
+				return typeError
+			}
+			v.SetInt(int64(t))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if t < 0 || v.OverflowUint(uint64(t)) {
+				return typeError
+			}
+			v.SetUint(uint64(t))
+		default:
+			return typeError
+		}
+
+	case string:
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(t)
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			v.SetBytes([]byte(t))
+		case v.Kind() == reflect.Array && v.Type().Elem().Kind() == reflect.Uint8:
+			if v.Len() != len(t) {
+				typeError.Value = fmt.Sprintf("string of %d bytes", len(t))
+				return typeError
+			}
+			reflect.Copy(v, reflect.ValueOf(t))
+		default:
+			return typeError
+		}
+
+	case []interface{}:
+		switch v.Kind() {
+		case reflect.Slice:
+			v.Set(reflect.MakeSlice(v.Type(), len(t), len(t)))
+		case reflect.Array:
+			v.SetZero()
+		default:
+			return typeError
+		}
+
+		for i := range min(len(t), v.Len()) {
+			if err := s.child(t[i], v.Index(i), PathSegment{Index: i, IsIndex: true}); err != nil {
+				return err
+			}
+		}
+
+	case map[string]interface{}:
+		switch {
+		case v.Kind() == reflect.Map && isDecodableKeyType(v.Type().Key()):
+			return s.dict(t, v)
+		case v.Kind() == reflect.Struct:
+			return s.structFields(t, v)
+		default:
+			return typeError
+		}
+
+	default:
+		return typeError
+	}
+
+	return nil
+}
+
+func (s *unmarshalState) dict(dict map[string]interface{}, v reflect.Value) error {
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(dict)))
+	}
+
+	keyType := v.Type().Key()
+	elemType := v.Type().Elem()
+
+	for key, value := range dict {
+		k, err := decodeMapKey(key, keyType)
+		if err != nil {
+			return err
+		}
+
+		elem := reflect.New(elemType).Elem()
+		if err := s.child(value, elem, PathSegment{Key: key}); err != nil {
+			return err
+		}
+		v.SetMapIndex(k, elem)
+	}
+
+	return nil
+}
+
+func (s *unmarshalState) structFields(dict map[string]interface{}, v reflect.Value) error {
+	for _, f := range cachedFields(v.Type()) {
+		value, ok := dict[f.name]
+		if !ok {
+			if f.required {
+				return &RequiredKeyError{Key: f.name, Type: v.Type()}
+			}
+			continue
+		}
+
+		fv, err := fieldByIndexAlloc(v, f.index)
+		if err != nil {
+			return prependPath(err, PathSegment{Key: f.name})
+		}
+
+		if err := s.child(value, fv, PathSegment{Key: f.name}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// v.FieldByIndex that allocates nil embedded pointers on the way
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("bencode: cannot set embedded pointer to unexported struct %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// Gives unmarshaler its part of the input, or tree encoded again when the
+// input is not at hand
+func (s *unmarshalState) unmarshaler(tree any, v reflect.Value, unmarshaler Unmarshaler) error {
+	data, ok := s.raw.lookup(s.path)
+
+	var err error
+	if !ok {
+		data, err = Marshal(tree)
+	}
+
+	if err == nil {
+		err = unmarshaler.UnmarshalBencode(data)
+	}
+
+	if err != nil {
+		return &UnmarshalerError{Type: v.Type(), Err: err, sourceFunc: "UnmarshalBencode"}
+	}
+	return nil
+}
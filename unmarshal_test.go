@@ -0,0 +1,149 @@
+package decodebencode_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+type unmarshalFile struct {
+	Length int64    `bencode:"length"`
+	Path   []string `bencode:"path"`
+}
+
+type unmarshalInfo struct {
+	Name        string          `bencode:"name"`
+	PieceLength uint32          `bencode:"piece length"`
+	Pieces      []byte          `bencode:"pieces"`
+	Hash        [4]byte         `bencode:"hash"`
+	Files       []unmarshalFile `bencode:"files"`
+	Private     *int8           `bencode:"private"`
+	Extra       map[string]any  `bencode:"extra"`
+	Sizes       map[string]int  `bencode:"sizes"`
+	Pair        [2]int          `bencode:"pair"`
+	Anything    any             `bencode:"anything"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	input := "d8:anythingli1e1:xe5:extrad1:ai1ee5:filesld6:lengthi7e4:pathl3:dir5:a.txteee" +
+		"4:hash4:\x00\x01\x02\x034:name5:a.txt4:pairli1ei2ei3ee12:piece lengthi16384e6:pieces2:\xff\x00" +
+		"7:privatei1e5:sizesd1:bi2eee"
+
+	var output unmarshalInfo
+	if err := decodebencode.Unmarshal([]byte(input), &output); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	one := int8(1)
+	expected := unmarshalInfo{
+		Name:        "a.txt",
+		PieceLength: 16384,
+		Pieces:      []byte{0xff, 0},
+		Hash:        [4]byte{0, 1, 2, 3},
+		Files:       []unmarshalFile{{Length: 7, Path: []string{"dir", "a.txt"}}},
+		Private:     &one,
+		Extra:       map[string]any{"a": 1},
+		Sizes:       map[string]int{"b": 2},
+		Pair:        [2]int{1, 2},
+		Anything:    []interface{}{1, "x"},
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, output)
+	}
+
+	encoded, err := decodebencode.Marshal(expected)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var roundTrip unmarshalInfo
+	if err := decodebencode.Unmarshal(encoded, &roundTrip); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(roundTrip, expected) {
+		t.Errorf("Expected %+v after round trip, got %+v", expected, roundTrip)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	type TestCase struct {
+		name      string
+		input     string
+		target    any
+		expectErr string
+	}
+
+	testCases := []TestCase{
+		{name: "not a pointer", input: "i1e", target: 1, expectErr: "bencode: Unmarshal(non-pointer or nil int)"},
+		{name: "nil", input: "i1e", target: nil, expectErr: "bencode: Unmarshal(nil)"},
+		{name: "empty input", input: "", target: new(int), expectErr: "bencode: cannot unmarshal empty input"},
+		{name: "string into int", input: "2:hi", target: new(int), expectErr: "bencode: cannot unmarshal string into Go value of type int"},
+		{name: "overflow", input: "i300e", target: new(uint8), expectErr: "bencode: cannot unmarshal integer 300 into Go value of type uint8"},
+		{name: "negative into uint", input: "i-1e", target: new(uint), expectErr: "bencode: cannot unmarshal integer -1 into Go value of type uint"},
+		{name: "wrong byte array length", input: "d4:hash2:abe", target: new(unmarshalInfo), expectErr: "bencode: cannot unmarshal string of 2 bytes into Go value of type [4]uint8 at `hash`"},
+		{name: "nested path", input: "d5:filesld6:length2:xxeee", target: new(unmarshalInfo), expectErr: "bencode: cannot unmarshal string into Go value of type int64 at `files[0].length`"},
+		{name: "dict into list", input: "de", target: new([]int), expectErr: "bencode: cannot unmarshal dict into Go value of type []int"},
+		{name: "list into map", input: "le", target: new(map[string]int), expectErr: "bencode: cannot unmarshal list into Go value of type map[string]int"},
+		{name: "broken input", input: "x", target: new([]int), expectErr: "parsing error, expected digit, got x on index 0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := decodebencode.Unmarshal([]byte(tc.input), tc.target)
+			if err == nil {
+				t.Fatalf("Expected error, got nil")
+			}
+			if err.Error() != tc.expectErr {
+				t.Errorf("Expected error %q, got %q", tc.expectErr, err)
+			}
+		})
+	}
+
+	var typeErr *decodebencode.UnmarshalTypeError
+	err := decodebencode.Unmarshal([]byte("li1e2:hie"), new([]int))
+	if !errors.As(err, &typeErr) {
+		t.Fatalf("Expected *UnmarshalTypeError, got %v", err)
+	}
+	if typeErr.Path.String() != "[1]" {
+		t.Errorf("Expected path [1], got %q", typeErr.Path)
+	}
+}
+
+// keeps the raw bencode it was given, rejects lists
+type rawDict []byte
+
+func (r *rawDict) UnmarshalBencode(data []byte) error {
+	if len(data) > 0 && data[0] == 'l' {
+		return errors.New("lists are not welcome")
+	}
+	*r = append((*r)[:0], data...)
+	return nil
+}
+
+func TestUnmarshalUnmarshaler(t *testing.T) {
+	var top rawDict
+	if err := decodebencode.Unmarshal([]byte("d1:bi1e1:ai2ee"), &top); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(top) != "d1:bi1e1:ai2ee" {
+		t.Errorf("Expected input as is, got %q", top)
+	}
+
+	var nested struct {
+		Info rawDict `bencode:"info"`
+	}
+	if err := decodebencode.Unmarshal([]byte("d4:infod1:bi1e1:ai2eee"), &nested); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(nested.Info) != "d1:bi1e1:ai2ee" {
+		t.Errorf("Expected nested value as is, got %q", nested.Info)
+	}
+
+	err := decodebencode.Unmarshal([]byte("d4:infoli1eee"), &nested)
+	var unmarshalerError *decodebencode.UnmarshalerError
+	if !errors.As(err, &unmarshalerError) || unmarshalerError.Path.String() != "info" {
+		t.Errorf("Expected *UnmarshalerError at `info`, got %v", err)
+	}
+}
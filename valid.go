@@ -0,0 +1,133 @@
+package decodebencode
+
+import "fmt"
+
+// Reports whether data is exactly one well-formed bencode value: integers
+// without leading zeros or negative zero, string lengths without leading zeros
+// and only strings as dict keys. The order of dict keys is not checked.
+func Valid(data []byte) bool {
+	return checkValid(data) == nil
+}
+
+// same as Valid, but tells what is wrong and where
+func checkValid(data []byte) error {
+	return checkBencode(data, true)
+}
+
+// Same as checkValid, but accepts the integers and string lengths DecodeBencode
+// accepts: leading zeros, plus signs and -0
+func checkDecodable(data []byte) error {
+	return checkBencode(data, false)
+}
+
+func checkBencode(data []byte, strict bool) error {
+	if len(data) == 0 {
+		return fmt.Errorf("invalid bencode: empty input")
+	}
+
+	end, err := validValue(data, 0, 0, strict)
+	if err != nil {
+		return err
+	}
+
+	if end != len(data) {
+		return fmt.Errorf("invalid bencode: unexpected data after the value on index %d", end)
+	}
+
+	return nil
+}
+
+// nesting deeper than that is rejected instead of exhausting the stack
+const maxValidDepth = 10000
+
+// checks the value starting at i, returns where it ends
+func validValue(data []byte, i int, depth int, strict bool) (int, error) {
+	if i >= len(data) {
+		return i, fmt.Errorf("invalid bencode: unexpected end of input on index %d", i)
+	}
+
+	if depth > maxValidDepth {
+		return i, fmt.Errorf("invalid bencode: nesting deeper than %d on index %d", maxValidDepth, i)
+	}
+
+	switch c := data[i]; {
+	case c == INT_CONTROL_SYMBOL:
+		end := indexByte(data, i+1, CLOSE_CONTROL_SYMBOL)
+		if end < 0 || !validInteger(data[i+1:end], strict) {
+			return i, fmt.Errorf("invalid bencode: malformed integer on index %d", i)
+		}
+		return end + 1, nil
+
+	case c == LIST_CONTROL_SYMBOL || c == DICT_CONTROL_SYMBOL:
+		i++
+		isKey := c == DICT_CONTROL_SYMBOL
+		for i < len(data) && data[i] != CLOSE_CONTROL_SYMBOL {
+			if c == DICT_CONTROL_SYMBOL && isKey && (data[i] < '0' || data[i] > '9') {
+				return i, fmt.Errorf("invalid bencode: dict key on index %d is not a string", i)
+			}
+
+			var err error
+			if i, err = validValue(data, i, depth+1, strict); err != nil {
+				return i, err
+			}
+			isKey = c == DICT_CONTROL_SYMBOL && !isKey
+		}
+
+		if i >= len(data) {
+			return i, fmt.Errorf("invalid bencode: missing closing symbol %v", string(CLOSE_CONTROL_SYMBOL))
+		}
+		if c == DICT_CONTROL_SYMBOL && !isKey {
+			return i, fmt.Errorf("invalid bencode: dict key without value before index %d", i)
+		}
+		return i + 1, nil
+
+	case c >= '0' && c <= '9':
+		colon := indexByte(data, i, STR_CONTROL_SYMBOL)
+		if colon < 0 || (strict && data[i] == '0' && colon != i+1) {
+			return i, fmt.Errorf("invalid bencode: malformed string length on index %d", i)
+		}
+
+		length, ok := parseInteger(data[i:colon])
+		if !ok || length > len(data)-colon-1 {
+			return i, fmt.Errorf("invalid bencode: malformed string length on index %d", i)
+		}
+		return colon + 1 + length, nil
+
+	default:
+		return i, fmt.Errorf("invalid bencode: unexpected symbol %q on index %d", c, i)
+	}
+}
+
+// canonical integer when strict, otherwise one DecodeBencode can decode
+func validInteger(digits []byte, strict bool) bool {
+	if strict {
+		return isCanonicalInteger(digits)
+	}
+	_, ok := parseInteger(digits)
+	return ok
+}
+
+// decimal integer as BEP-3 wants it: no sign but minus, no leading zeros, no
+// -0. Any number of digits is fine, bencode integers have no size limit.
+func isCanonicalInteger[T bencodeInput](digits T) bool {
+	start := 0
+	if len(digits) > 0 && digits[0] == '-' {
+		start = 1
+	}
+
+	if start == len(digits) {
+		return false
+	}
+
+	for i := start; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return false
+		}
+	}
+
+	if digits[start] == '0' {
+		return len(digits) == 1
+	}
+
+	return true
+}
@@ -0,0 +1,53 @@
+package decodebencode_test
+
+import (
+	"strings"
+	"testing"
+
+	decodebencode "github.com/jabakot/decode-bencode"
+)
+
+func TestValid(t *testing.T) {
+	type TestCase struct {
+		name     string
+		input    string
+		expected bool
+	}
+
+	testCases := []TestCase{
+		{name: "integer", input: "i42e", expected: true},
+		{name: "zero", input: "i0e", expected: true},
+		{name: "negative integer", input: "i-42e", expected: true},
+		{name: "integer bigger than int64", input: "i123456789012345678901234567890e", expected: true},
+		{name: "string", input: "3:hi!", expected: true},
+		{name: "empty string", input: "0:", expected: true},
+		{name: "binary string", input: "2:\x00\xff", expected: true},
+		{name: "list", input: "li1e1:xe", expected: true},
+		{name: "empty list", input: "le", expected: true},
+		{name: "dict", input: "d1:ai1e1:bli1eee", expected: true},
+		{name: "unsorted dict is still valid", input: "d1:bi1e1:ai2ee", expected: true},
+		{name: "empty input", input: "", expected: false},
+		{name: "integer with leading zero", input: "i03e", expected: false},
+		{name: "negative zero", input: "i-0e", expected: false},
+		{name: "integer with plus", input: "i+3e", expected: false},
+		{name: "empty integer", input: "ie", expected: false},
+		{name: "integer without end", input: "i42", expected: false},
+		{name: "string length with leading zero", input: "03:abc", expected: false},
+		{name: "string too short", input: "4:abc", expected: false},
+		{name: "list without end", input: "li1e", expected: false},
+		{name: "dict with integer key", input: "di1ei2ee", expected: false},
+		{name: "dict with key and no value", input: "d1:ae", expected: false},
+		{name: "two values", input: "i1ei2e", expected: false},
+		{name: "unknown symbol", input: "x", expected: false},
+		{name: "deep nesting", input: strings.Repeat("l", 100000) + strings.Repeat("e", 100000), expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			output := decodebencode.Valid([]byte(tc.input))
+			if output != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, output)
+			}
+		})
+	}
+}